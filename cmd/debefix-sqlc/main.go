@@ -0,0 +1,120 @@
+// Command debefix-sqlc generates typed debefix row constructors from sqlc schema files.
+//
+//	debefix-sqlc -schema db/schema.sql -package fixtures -out fixtures/tables.gen.go
+//
+// The schema can be a file or a directory, in which case all ".sql" files are read in name order, like sqlc does
+// for migration directories. Down migrations, like "1_init.down.sql" files and the down sections of goose
+// migrations, are skipped.
+//
+// The Go type of SQL types can be overridden with the repeatable -type flag, in the "sqltype=gotype" format, or
+// "sqltype=gotype@importpath" if the type needs an import:
+//
+//	debefix-sqlc -type jsonb=map[string]any -type interval=pgtype.Interval@github.com/jackc/pgx/v5/pgtype
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rrgmc/debefix-db/v2/sqlc"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		schemaPath    = flag.String("schema", "schema.sql", "schema file or directory")
+		packageName   = flag.String("package", "fixtures", "generated package name")
+		out           = flag.String("out", "", "output file (default stdout)")
+		includeSchema = flag.Bool("include-schema", false, "prefix generated names with the table schema name")
+	)
+	var typeOverrides typeOverrideFlag
+	flag.Var(&typeOverrides, "type", "Go type of a SQL type, as sqltype=gotype[@importpath] (repeatable)")
+	flag.Parse()
+
+	files, err := schemaFiles(*schemaPath)
+	if err != nil {
+		return err
+	}
+
+	var src bytes.Buffer
+	for _, file := range files {
+		fileSrc, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		src.Write(fileSrc)
+		src.WriteString(";\n")
+	}
+
+	schema, err := sqlc.ParseSchema(&src)
+	if err != nil {
+		return err
+	}
+
+	gen, err := sqlc.Generate(schema, append([]sqlc.GenerateOption{
+		sqlc.WithPackageName(*packageName),
+		sqlc.WithIncludeSchema(*includeSchema),
+	}, typeOverrides...)...)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		return os.WriteFile(*out, gen, 0o644)
+	}
+	_, err = os.Stdout.Write(gen)
+	return err
+}
+
+// schemaFiles returns the schema file, or the list of ".sql" files if the path is a directory, except the
+// ".down.sql" down migrations.
+func schemaFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || strings.HasSuffix(entry.Name(), ".down.sql") {
+			continue
+		}
+		ret = append(ret, filepath.Join(path, entry.Name()))
+	}
+	slices.Sort(ret)
+	return ret, nil
+}
+
+// typeOverrideFlag is a repeatable flag of type overrides in the "sqltype=gotype[@importpath]" format.
+type typeOverrideFlag []sqlc.GenerateOption
+
+func (f *typeOverrideFlag) String() string {
+	return ""
+}
+
+func (f *typeOverrideFlag) Set(value string) error {
+	sqlType, goType, ok := strings.Cut(value, "=")
+	if !ok || sqlType == "" || goType == "" {
+		return fmt.Errorf("invalid type override '%s', must be sqltype=gotype[@importpath]", value)
+	}
+	goType, importPath, _ := strings.Cut(goType, "@")
+	*f = append(*f, sqlc.WithTypeOverride(sqlType, goType, importPath))
+	return nil
+}
@@ -0,0 +1,4 @@
+// Package testent is an ent generated client used by the tests.
+package testent
+
+//go:generate go run -mod=mod entgo.io/ent/cmd/ent generate ./schema
@@ -0,0 +1,20 @@
+// Code generated by ent, DO NOT EDIT.
+
+package testent
+
+import (
+	"github.com/rrgmc/debefix-db/ent/v2/internal/testent/schema"
+	"github.com/rrgmc/debefix-db/ent/v2/internal/testent/tag"
+)
+
+// The init function reads all schema descriptors with runtime code
+// (default values, validators, hooks and policies) and stitches it
+// to their package variables.
+func init() {
+	tagFields := schema.Tag{}.Fields()
+	_ = tagFields
+	// tagDescColor is the schema descriptor for color field.
+	tagDescColor := tagFields[1].Descriptor()
+	// tag.DefaultColor holds the default value on creation for the color field.
+	tag.DefaultColor = tagDescColor.Default.(string)
+}
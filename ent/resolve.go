@@ -0,0 +1,447 @@
+package ent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/privacy"
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Client is an abstraction over the ent generated client, or a transactional client.
+type Client interface {
+	Mutate(ctx context.Context, m ent.Mutation) (ent.Value, error)
+}
+
+// Table maps a debefix table to an ent schema type, using the generated client mutations.
+//
+//	ent.Table{
+//		Create: func() ent.Mutation { return client.Tag.Create().Mutation() },
+//		Update: func() ent.Mutation { return client.Tag.Update().Mutation() },
+//	}
+type Table struct {
+	// Create returns a new mutation for creating a node of the schema type.
+	Create func() ent.Mutation
+	// Update returns a new mutation for updating nodes of the schema type. The mutation must have the
+	// generated "WhereP" method. It is optional if the table don't have updates.
+	Update func() ent.Mutation
+	// Fields maps the fixture field names to the ent schema field names, if they are different. A field mapped to
+	// "id" is set using the generated "SetID" method.
+	Fields map[string]string
+	// Edges maps fixture field names to the names of ent edges of the schema type, for edges which don't have an
+	// edge field in the schema, like the "owner" edge which ent stores in the "user_posts" column. The field value
+	// is the ID of the connected node, set using the generated "Set<Edge>ID" or "Add<Edge>IDs" method.
+	Edges map[string]string
+}
+
+// EdgeTable maps a debefix table to the join table of an ent M2M edge, like "post_tags". Each row connects 2
+// existing nodes, using an update mutation of the type which declares the edge.
+//
+//	ent.EdgeTable{
+//		Update: func() ent.Mutation { return client.Post.Update().Mutation() },
+//		Edge:   "tags",
+//		From:   "post_id",
+//		To:     "tag_id",
+//	}
+type EdgeTable struct {
+	// Update returns a new mutation for updating nodes of the type which declares the edge. The mutation must have
+	// the generated "WhereP" method.
+	Update func() ent.Mutation
+	// Edge is the name of the edge in the type which declares it.
+	Edge string
+	// From is the fixture field name which contains the ID of the node which declares the edge.
+	From string
+	// To is the fixture field name which contains the ID of the connected node.
+	To string
+}
+
+// ResolveDBFunc is a db.ResolveDBCallback helper to generate database records using an ent client.
+// Each table must be mapped to a schema type with WithTable. Records are created and updated using the generated
+// client dynamic mutation API, so ent hooks and privacy rules are applied, unless bypassed using WithContext or
+// WithBypassPrivacy.
+// The values of the returned fields are read from the created node, or from the mutation, like the generated ID and
+// default values.
+func ResolveDBFunc(client Client, options ...Option) db.ResolveDBCallback {
+	r := &resolver{
+		client:     client,
+		tables:     map[string]Table{},
+		edgeTables: map[string]EdgeTable{},
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+		returnFieldNames map[string]debefix.ResolveValue) (returnValues map[string]any, err error) {
+		return r.resolve(ctx, resolveInfo, fields, returnFieldNames)
+	}
+}
+
+// ResolveFunc is a debefix.ResolveCallback helper to generate database records using an ent client.
+func ResolveFunc(client Client, options ...Option) debefix.ResolveCallback {
+	return db.ResolveFunc(ResolveDBFunc(client, options...))
+}
+
+// Option is an option for ResolveDBFunc.
+type Option func(r *resolver)
+
+// WithTable maps a table to an ent schema type.
+func WithTable(tableID debefix.TableID, table Table) Option {
+	return func(r *resolver) {
+		r.tables[tableID.TableID()] = table
+	}
+}
+
+// WithEdgeTable maps a table to the join table of an ent M2M edge.
+func WithEdgeTable(tableID debefix.TableID, edgeTable EdgeTable) Option {
+	return func(r *resolver) {
+		r.edgeTables[tableID.TableID()] = edgeTable
+	}
+}
+
+// WithContext sets a function to change the context used for the mutations. It can be used to bypass ent hooks
+// which check for a context value.
+func WithContext(f func(ctx context.Context) context.Context) Option {
+	return func(r *resolver) {
+		r.contextFuncs = append(r.contextFuncs, f)
+	}
+}
+
+// WithBypassPrivacy sets an allow privacy decision in the mutations context, bypassing all privacy rules.
+func WithBypassPrivacy() Option {
+	return WithContext(func(ctx context.Context) context.Context {
+		return privacy.DecisionContext(ctx, privacy.Allow)
+	})
+}
+
+type resolver struct {
+	client       Client
+	tables       map[string]Table
+	edgeTables   map[string]EdgeTable
+	contextFuncs []func(ctx context.Context) context.Context
+}
+
+// wherePMutation is implemented by the generated mutations, and allows adding generic predicates.
+type wherePMutation interface {
+	WhereP(ps ...func(*sql.Selector))
+}
+
+func (r *resolver) resolve(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+	returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+	for _, f := range r.contextFuncs {
+		ctx = f(ctx)
+	}
+
+	if edgeTable, ok := r.edgeTables[resolveInfo.TableID.TableID()]; ok {
+		return nil, r.resolveEdge(ctx, resolveInfo, edgeTable, fields, returnFields)
+	}
+
+	table, ok := r.tables[resolveInfo.TableID.TableID()]
+	if !ok {
+		return nil, fmt.Errorf("no ent type registered for table '%s'", resolveInfo.TableID.TableID())
+	}
+
+	var m ent.Mutation
+	switch resolveInfo.Type {
+	case debefix.ResolveTypeAdd:
+		if table.Create == nil {
+			return nil, fmt.Errorf("no create mutation for table '%s'", resolveInfo.TableID.TableID())
+		}
+		m = table.Create()
+	case debefix.ResolveTypeUpdate:
+		if table.Update == nil {
+			return nil, fmt.Errorf("no update mutation for table '%s'", resolveInfo.TableID.TableID())
+		}
+		if len(resolveInfo.UpdateKeyFields) == 0 {
+			return nil, fmt.Errorf("no key fields found for update in '%s'", resolveInfo.TableID.TableID())
+		}
+		if len(returnFields) > 0 {
+			return nil, fmt.Errorf("returning fields on update is not supported for table '%s'",
+				resolveInfo.TableID.TableID())
+		}
+		m = table.Update()
+		wm, ok := m.(wherePMutation)
+		if !ok {
+			return nil, fmt.Errorf("update mutation of type '%s' don't support generic predicates", m.Type())
+		}
+		for _, fn := range slices.Sorted(slices.Values(resolveInfo.UpdateKeyFields)) {
+			fv, ok := fields[fn]
+			if !ok {
+				return nil, fmt.Errorf("field %s is not set", fn)
+			}
+			wm.WhereP(sql.FieldEQ(table.fieldName(fn), fv))
+		}
+	default:
+		return nil, fmt.Errorf("unknown resolve type: %v", resolveInfo.Type)
+	}
+
+	for _, fn := range slices.Sorted(maps.Keys(fields)) {
+		if resolveInfo.Type == debefix.ResolveTypeUpdate && slices.Contains(resolveInfo.UpdateKeyFields, fn) {
+			continue
+		}
+		var err error
+		if edge, ok := table.Edges[fn]; ok {
+			err = setEdgeID(m, edge, fields[fn])
+		} else if fieldName := table.fieldName(fn); fieldName == idFieldName {
+			err = callMutationMethod(m, "SetID", fields[fn])
+		} else {
+			err = m.SetField(fieldName, fields[fn])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error setting field '%s' of type '%s': %w", fn, m.Type(), err)
+		}
+	}
+
+	value, err := r.client.Mutate(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]any{}
+	for fn := range returnFields {
+		fv, ok := nodeField(value, table.fieldName(fn))
+		if !ok {
+			fv, ok = m.Field(table.fieldName(fn))
+		}
+		if !ok {
+			return nil, fmt.Errorf("returned field '%s' not found in type '%s'", fn, m.Type())
+		}
+		ret[fn] = fv
+	}
+	return ret, nil
+}
+
+// resolveEdge connects the nodes of a row of an edge table.
+func (r *resolver) resolveEdge(ctx context.Context, resolveInfo db.ResolveDBInfo, edgeTable EdgeTable,
+	fields map[string]any, returnFields map[string]debefix.ResolveValue) error {
+	if resolveInfo.Type != debefix.ResolveTypeAdd {
+		return fmt.Errorf("only adding rows is supported for edge table '%s'", resolveInfo.TableID.TableID())
+	}
+	if len(returnFields) > 0 {
+		return fmt.Errorf("returning fields is not supported for edge table '%s'", resolveInfo.TableID.TableID())
+	}
+	if edgeTable.Update == nil {
+		return fmt.Errorf("no update mutation for edge table '%s'", resolveInfo.TableID.TableID())
+	}
+
+	fromID, ok := fields[edgeTable.From]
+	if !ok {
+		return fmt.Errorf("field %s is not set", edgeTable.From)
+	}
+	toID, ok := fields[edgeTable.To]
+	if !ok {
+		return fmt.Errorf("field %s is not set", edgeTable.To)
+	}
+
+	m := edgeTable.Update()
+	wm, ok := m.(wherePMutation)
+	if !ok {
+		return fmt.Errorf("update mutation of type '%s' don't support generic predicates", m.Type())
+	}
+	wm.WhereP(sql.FieldEQ(idFieldName, fromID))
+	if err := setEdgeID(m, edgeTable.Edge, toID); err != nil {
+		return fmt.Errorf("error setting edge '%s' of type '%s': %w", edgeTable.Edge, m.Type(), err)
+	}
+
+	value, err := r.client.Mutate(ctx, m)
+	if err != nil {
+		return err
+	}
+	if affected, ok := value.(int); ok && affected != 1 {
+		return fmt.Errorf("node with id '%v' of type '%s' not found for edge table '%s'", fromID, m.Type(),
+			resolveInfo.TableID.TableID())
+	}
+	return nil
+}
+
+func (t Table) fieldName(name string) string {
+	if fn, ok := t.Fields[name]; ok {
+		return fn
+	}
+	return name
+}
+
+// nodeField returns the value of a field of a generated ent node, using the field "json" tag, which ent sets as
+// the schema field name.
+func nodeField(node ent.Value, fieldName string) (any, bool) {
+	rv := reflect.ValueOf(node)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == fieldName {
+			return rv.Field(i).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// idFieldName is the name of the ID field of all ent schema types.
+const idFieldName = "id"
+
+// setEdgeID sets the ID of the node connected by an edge, using the generated "Set<Edge>ID" method of unique
+// edges, or "Add<Edge>IDs" of non-unique ones, where the edge name is singularized.
+func setEdgeID(m ent.Mutation, edge string, id any) error {
+	name := pascal(edge)
+	for _, methodName := range []string{"Set" + name + "ID", "Add" + singular(name) + "IDs", "Add" + name + "IDs"} {
+		if reflect.ValueOf(m).MethodByName(methodName).IsValid() {
+			return callMutationMethod(m, methodName, id)
+		}
+	}
+	return fmt.Errorf("unknown edge '%s'", edge)
+}
+
+// callMutationMethod calls a generated mutation method which receives a single value, or a variadic one.
+func callMutationMethod(m ent.Mutation, methodName string, value any) error {
+	method := reflect.ValueOf(m).MethodByName(methodName)
+	if !method.IsValid() {
+		return fmt.Errorf("method '%s' not found", methodName)
+	}
+	if method.Type().NumIn() != 1 {
+		return fmt.Errorf("method '%s' must have a single parameter", methodName)
+	}
+	paramType := method.Type().In(0)
+	if method.Type().IsVariadic() {
+		paramType = paramType.Elem()
+	}
+	arg, err := convertValue(value, paramType)
+	if err != nil {
+		return fmt.Errorf("error calling method '%s': %w", methodName, err)
+	}
+	method.Call([]reflect.Value{arg})
+	return nil
+}
+
+// convertValue converts value to the type t. Only assignable values, and integer values which fit in the target
+// integer type, are accepted.
+func convertValue(value any, t reflect.Type) (reflect.Value, error) {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return reflect.Value{}, fmt.Errorf("cannot use nil as '%s'", t)
+	}
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	switch {
+	case isIntKind(rv.Kind()) && isIntKind(t.Kind()):
+		if intOverflows(rv, t) {
+			return reflect.Value{}, fmt.Errorf("value %v overflows '%s'", value, t)
+		}
+		return rv.Convert(t), nil
+	case rv.Kind() == reflect.String && t.Kind() == reflect.String:
+		return rv.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use value of type '%s' as '%s'", rv.Type(), t)
+}
+
+// intOverflows returns whether the integer value rv doesn't fit in the integer type t.
+func intOverflows(rv reflect.Value, t reflect.Type) bool {
+	target := reflect.New(t).Elem()
+	if rv.CanInt() {
+		if target.CanInt() {
+			return target.OverflowInt(rv.Int())
+		}
+		return rv.Int() < 0 || target.OverflowUint(uint64(rv.Int()))
+	}
+	if target.CanUint() {
+		return target.OverflowUint(rv.Uint())
+	}
+	return rv.Uint() > math.MaxInt64 || target.OverflowInt(int64(rv.Uint()))
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// acronyms are the words which ent writes in upper case in generated names.
+var acronyms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "AWS": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GB": true, "GUID": true, "HCL": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "KB": true, "LHS": true, "MAC": true, "MB": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "SSO": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "URI": true, "URL": true, "UTF8": true, "UUID": true,
+	"VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// pascal converts a snake case name to the pascal case used by ent generated names, like "api_keys" to "APIKeys".
+func pascal(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if upper := strings.ToUpper(word); acronyms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]))
+		b.WriteString(word[1:])
+	}
+	return b.String()
+}
+
+// singularUncountable are words which have the same singular and plural forms.
+var singularUncountable = map[string]bool{
+	"data": true, "equipment": true, "information": true, "metadata": true, "news": true, "series": true,
+	"species": true, "status": true,
+}
+
+// singularIrregular are irregular plural words.
+var singularIrregular = map[string]string{
+	"children": "child", "men": "man", "people": "person", "women": "woman",
+}
+
+// singular returns the singular of the last word of a plural name, using basic English rules, keeping the case of
+// its first letter. Names ending in "ies" with more than 4 letters end in "y", like "categories" to "category",
+// while shorter ones like "ties" only lose the "s".
+// The rules are the same as the sqlc package singular, and must be kept in sync.
+func singular(name string) string {
+	idx := strings.LastIndexAny(name, "_-. ") + 1
+	prefix, word := name[:idx], name[idx:]
+	lower := strings.ToLower(word)
+	switch {
+	case singularUncountable[lower]:
+		return name
+	case singularIrregular[lower] != "":
+		irregular := singularIrregular[lower]
+		if word[:1] != lower[:1] {
+			irregular = strings.ToUpper(irregular[:1]) + irregular[1:]
+		}
+		return prefix + irregular
+	case strings.HasSuffix(lower, "ies") && len(lower) > 4:
+		return prefix + word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "ches"),
+		strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "zzes"):
+		return prefix + word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return name
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		return prefix + word[:len(word)-1]
+	}
+	return name
+}
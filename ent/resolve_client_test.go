@@ -0,0 +1,119 @@
+package ent
+
+import (
+	"context"
+	stdsql "database/sql"
+	"slices"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"github.com/rrgmc/debefix-db/ent/v2/internal/testent"
+	"github.com/rrgmc/debefix-db/ent/v2/internal/testent/post"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestResolveGeneratedClient(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, err := stdsql.Open("sqlite", "file:ent?mode=memory&_pragma=foreign_keys(1)")
+	assert.NilError(t, err)
+	defer sqldb.Close()
+	// each connection to an in-memory database has its own database.
+	sqldb.SetMaxOpenConns(1)
+
+	client := testent.NewClient(testent.Driver(entsql.OpenDB(dialect.SQLite, sqldb)))
+	assert.NilError(t, client.Schema.Create(ctx))
+
+	tableUsers := debefix.TableName("users")
+	tablePostTags := debefix.TableName("post_tags")
+
+	data := debefix.NewData()
+
+	data.AddValues(tableUsers,
+		debefix.MapValues{
+			"id":     debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("john"),
+			"name":   "John",
+		},
+	)
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"id":     debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("go"),
+			"name":   "Go",
+			"color":  debefix.ResolveValueResolve(),
+		},
+		debefix.MapValues{
+			"id":     debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("javascript"),
+			"name":   "JavaScript",
+			"color":  "yellow",
+		},
+	)
+	postIID := data.AddWithID(tablePosts,
+		debefix.MapValues{
+			"post_id": 10,
+			"user_id": debefix.ValueRefID(tableUsers, "john", "id"),
+			"title":   "First post",
+		},
+	)
+	data.AddValues(tablePostTags,
+		debefix.MapValues{
+			"post_id": postIID.ValueForField("post_id"),
+			"tag_id":  debefix.ValueRefID(tableTags, "go", "id"),
+		},
+		debefix.MapValues{
+			"post_id": postIID.ValueForField("post_id"),
+			"tag_id":  debefix.ValueRefID(tableTags, "javascript", "id"),
+		},
+	)
+
+	data.Update(postIID.UpdateQuery([]string{"post_id"}),
+		debefix.UpdateActionSetValues{Values: debefix.MapValues{
+			"title": "First post updated",
+		}})
+
+	resolved, err := debefix.Resolve(ctx, data, ResolveFunc(client,
+		WithTable(tableUsers, Table{
+			Create: func() ent.Mutation { return client.User.Create().Mutation() },
+		}),
+		WithTable(tableTags, Table{
+			Create: func() ent.Mutation { return client.Tag.Create().Mutation() },
+		}),
+		WithTable(tablePosts, Table{
+			Create: func() ent.Mutation { return client.Post.Create().Mutation() },
+			Update: func() ent.Mutation { return client.Post.Update().Mutation() },
+			Fields: map[string]string{"post_id": "id"},
+			Edges:  map[string]string{"user_id": "owner"},
+		}),
+		WithEdgeTable(tablePostTags, EdgeTable{
+			Update: func() ent.Mutation { return client.Post.Update().Mutation() },
+			Edge:   "tags",
+			From:   "post_id",
+			To:     "tag_id",
+		})))
+	assert.NilError(t, err)
+
+	goTagColor, err := resolved.FindRefIDRowValue(debefix.ValueRefID(tableTags, "go", "color"))
+	assert.NilError(t, err)
+	assert.Equal(t, "blue", goTagColor)
+
+	p, err := client.Post.Query().
+		Where(post.ID(10)).
+		WithOwner().
+		WithTags().
+		Only(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, "First post updated", p.Title)
+	assert.Equal(t, "John", p.Edges.Owner.Name)
+	var tagNames []string
+	for _, tag := range p.Edges.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	slices.Sort(tagNames)
+	assert.DeepEqual(t, []string{"Go", "JavaScript"}, tagNames)
+}
@@ -0,0 +1,181 @@
+package ent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/privacy"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags  = debefix.TableName("tags")
+	tablePosts = debefix.TableName("posts")
+)
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	client := &testClient{}
+
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"id":     debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("all"),
+			"name":   "All",
+			"color":  debefix.ResolveValueResolve(),
+		},
+	)
+
+	postIID := data.AddWithID(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "all", "id"),
+			"title":   "First post",
+		},
+	)
+
+	data.Update(postIID.UpdateQuery([]string{"post_id"}),
+		debefix.UpdateActionSetValues{Values: debefix.MapValues{
+			"title": "First post updated",
+		}})
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(client,
+		WithTable(tableTags, Table{
+			Create: func() ent.Mutation { return newTestMutation("Tag", ent.OpCreate) },
+		}),
+		WithTable(tablePosts, Table{
+			Create: func() ent.Mutation { return newTestMutation("Post", ent.OpCreate) },
+			Update: func() ent.Mutation { return newTestMutation("Post", ent.OpUpdate) },
+			Fields: map[string]string{"post_id": "id"},
+		}),
+		WithBypassPrivacy()))
+	assert.NilError(t, err)
+
+	assert.Equal(t, 3, len(client.mutations))
+
+	assert.Equal(t, "Tag", client.mutations[0].Type())
+	assert.DeepEqual(t, map[string]ent.Value{"name": "All", "color": "blue"}, client.mutations[0].fields)
+
+	assert.Equal(t, "Post", client.mutations[1].Type())
+	assert.DeepEqual(t, map[string]ent.Value{"id": 1, "tag_id": 10, "title": "First post"},
+		client.mutations[1].fields)
+
+	assert.Equal(t, ent.OpUpdate, client.mutations[2].Op())
+	assert.DeepEqual(t, map[string]ent.Value{"tag_id": 10, "title": "First post updated"}, client.mutations[2].fields)
+	selector := sql.Dialect(dialect.Postgres).Select().From(sql.Table("posts"))
+	for _, p := range client.mutations[2].predicates {
+		p(selector)
+	}
+	query, args := selector.Query()
+	assert.Equal(t, `SELECT * FROM "posts" WHERE "posts"."id" = $1`, query)
+	assert.DeepEqual(t, []any{1}, args)
+}
+
+func TestResolveUnknownTable(t *testing.T) {
+	ctx := context.Background()
+
+	data := debefix.NewData()
+	data.Add(tableTags, debefix.MapValues{"name": "All"})
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(&testClient{}))
+	assert.ErrorContains(t, err, "no ent type registered for table 'tags'")
+}
+
+type testTag struct {
+	ID    int    `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+type testClient struct {
+	mutations []*testMutation
+}
+
+func (c *testClient) Mutate(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+	if decision, ok := privacy.DecisionFromContext(ctx); !ok || decision != nil {
+		return nil, errors.New("privacy not bypassed")
+	}
+
+	tm := m.(*testMutation)
+	c.mutations = append(c.mutations, tm)
+
+	switch {
+	case tm.Op() == ent.OpCreate && tm.Type() == "Tag":
+		// simulate a default value set by a hook.
+		_ = tm.SetField("color", "blue")
+		name, _ := tm.Field("name")
+		return &testTag{ID: 10, Name: name.(string)}, nil
+	case tm.Op() == ent.OpUpdate:
+		return 1, nil
+	}
+	return nil, nil
+}
+
+type testMutation struct {
+	typ        string
+	op         ent.Op
+	fields     map[string]ent.Value
+	predicates []func(*sql.Selector)
+}
+
+func newTestMutation(typ string, op ent.Op) *testMutation {
+	return &testMutation{typ: typ, op: op, fields: map[string]ent.Value{}}
+}
+
+func (m *testMutation) WhereP(ps ...func(*sql.Selector)) {
+	m.predicates = append(m.predicates, ps...)
+}
+
+func (m *testMutation) Op() ent.Op       { return m.op }
+func (m *testMutation) Type() string     { return m.typ }
+func (m *testMutation) Fields() []string { return nil }
+func (m *testMutation) Field(name string) (ent.Value, bool) {
+	v, ok := m.fields[name]
+	return v, ok
+}
+func (m *testMutation) SetField(name string, value ent.Value) error {
+	m.fields[name] = value
+	return nil
+}
+func (m *testMutation) SetID(id int) {
+	m.fields["id"] = id
+}
+func (m *testMutation) AddedFields() []string                       { return nil }
+func (m *testMutation) AddedField(name string) (ent.Value, bool)    { return nil, false }
+func (m *testMutation) AddField(name string, value ent.Value) error { return nil }
+func (m *testMutation) ClearedFields() []string                     { return nil }
+func (m *testMutation) FieldCleared(name string) bool               { return false }
+func (m *testMutation) ClearField(name string) error                { return nil }
+func (m *testMutation) ResetField(name string) error                { return nil }
+func (m *testMutation) AddedEdges() []string                        { return nil }
+func (m *testMutation) AddedIDs(name string) []ent.Value            { return nil }
+func (m *testMutation) RemovedEdges() []string                      { return nil }
+func (m *testMutation) RemovedIDs(name string) []ent.Value          { return nil }
+func (m *testMutation) ClearedEdges() []string                      { return nil }
+func (m *testMutation) EdgeCleared(name string) bool                { return false }
+func (m *testMutation) ClearEdge(name string) error                 { return nil }
+func (m *testMutation) ResetEdge(name string) error                 { return nil }
+func (m *testMutation) OldField(ctx context.Context, name string) (ent.Value, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestSingular(t *testing.T) {
+	for plural, expected := range map[string]string{
+		"Tags":       "Tag",
+		"Categories": "Category",
+		"Ties":       "Tie",
+		"Addresses":  "Address",
+		"Children":   "Child",
+		"Status":     "Status",
+	} {
+		assert.Equal(t, expected, singular(plural), plural)
+	}
+}
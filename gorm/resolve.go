@@ -0,0 +1,242 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ResolveDBFunc is a db.ResolveDBCallback helper to generate database records using GORM.
+// Each table must have a model registered with WithModel or WithModels. The model schema is used to find the
+// column names and primary keys, and records are inserted and updated using the model, so GORM hooks, serializers
+// and custom types are applied.
+// Inserts leave the fields with default values which are not set in the fixture to the database, fixture fields set to
+// zero values are stored even if the field has a default value, and updates change only the fixture fields.
+func ResolveDBFunc(gdb *gorm.DB, options ...Option) db.ResolveDBCallback {
+	r := &resolver{
+		db:     gdb,
+		models: map[string]any{},
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+		returnFieldNames map[string]debefix.ResolveValue) (returnValues map[string]any, err error) {
+		return r.resolve(ctx, resolveInfo, fields, returnFieldNames)
+	}
+}
+
+// ResolveFunc is a debefix.ResolveCallback helper to generate database records using GORM.
+func ResolveFunc(gdb *gorm.DB, options ...Option) debefix.ResolveCallback {
+	return db.ResolveFunc(ResolveDBFunc(gdb, options...))
+}
+
+// Option is an option for ResolveDBFunc.
+type Option func(r *resolver)
+
+// WithModel registers the model to be used for the table. The model must be a struct or a pointer to a struct.
+func WithModel(tableID debefix.TableID, model any) Option {
+	return func(r *resolver) {
+		r.models[tableID.TableID()] = model
+	}
+}
+
+// WithModels registers models which are matched by the GORM table name, which must be equal to the
+// [debefix.TableID.TableName] of the table, or to its name without the schema, like "posts" for "public.posts".
+func WithModels(models ...any) Option {
+	return func(r *resolver) {
+		r.tableModels = append(r.tableModels, models...)
+	}
+}
+
+type resolver struct {
+	db          *gorm.DB
+	models      map[string]any
+	tableModels []any
+}
+
+func (r *resolver) resolve(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+	returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+	sch, err := r.schema(resolveInfo.TableID)
+	if err != nil {
+		return nil, err
+	}
+
+	// create a new model instance with the fixture values.
+	model := reflect.New(sch.ModelType)
+	fieldNames := slices.Sorted(maps.Keys(fields))
+	for _, fn := range fieldNames {
+		field := sch.LookUpField(fn)
+		if field == nil {
+			return nil, fmt.Errorf("field '%s' not found in model for table '%s'", fn, resolveInfo.TableID.TableID())
+		}
+		if err := field.Set(ctx, model.Elem(), fields[fn]); err != nil {
+			return nil, fmt.Errorf("error setting field '%s' in model for table '%s': %w", fn,
+				resolveInfo.TableID.TableID(), err)
+		}
+	}
+
+	returnFieldNames := slices.Sorted(maps.Keys(returnFields))
+	for _, fn := range returnFieldNames {
+		if sch.LookUpField(fn) == nil {
+			return nil, fmt.Errorf("return field '%s' not found in model for table '%s'", fn,
+				resolveInfo.TableID.TableID())
+		}
+	}
+
+	tx := r.db.WithContext(ctx)
+
+	// query used to reload fields which were not returned by the database.
+	var reloadWhere map[string]any
+
+	switch resolveInfo.Type {
+	case debefix.ResolveTypeAdd:
+		// insert the fixture fields and the fields without default values, which may be set by hooks.
+		var createFieldNames []string
+		// GORM replaces zero values with the default value, so they must be set after the insert.
+		zeroDefaultFields := map[string]any{}
+		for _, field := range sch.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if _, ok := fields[field.Name]; !ok {
+				if _, ok = fields[field.DBName]; !ok {
+					if !field.HasDefaultValue {
+						createFieldNames = append(createFieldNames, field.DBName)
+					}
+					continue
+				}
+			}
+			createFieldNames = append(createFieldNames, field.DBName)
+			if fv, isZero := field.ValueOf(ctx, model.Elem()); isZero && field.HasDefaultValue {
+				zeroDefaultFields[field.DBName] = fv
+			}
+		}
+		if err := tx.Select(createFieldNames).Create(model.Interface()).Error; err != nil {
+			return nil, err
+		}
+		reloadWhere = map[string]any{}
+		for _, pf := range sch.PrimaryFields {
+			pv, isZero := pf.ValueOf(ctx, model.Elem())
+			if isZero {
+				reloadWhere = nil
+				break
+			}
+			reloadWhere[pf.DBName] = pv
+		}
+		if len(zeroDefaultFields) > 0 {
+			if len(reloadWhere) == 0 {
+				return nil, fmt.Errorf("cannot set zero values with defaults for table '%s' without primary key values",
+					resolveInfo.TableID.TableID())
+			}
+			err := tx.Model(model.Interface()).Where(reloadWhere).UpdateColumns(zeroDefaultFields).Error
+			if err != nil {
+				return nil, fmt.Errorf("error setting zero values with defaults for table '%s': %w",
+					resolveInfo.TableID.TableID(), err)
+			}
+			for dbName, fv := range zeroDefaultFields {
+				if err := sch.LookUpField(dbName).Set(ctx, model.Elem(), fv); err != nil {
+					return nil, fmt.Errorf("error setting field '%s' in model for table '%s': %w", dbName,
+						resolveInfo.TableID.TableID(), err)
+				}
+			}
+		}
+	case debefix.ResolveTypeUpdate:
+		if len(resolveInfo.UpdateKeyFields) == 0 {
+			return nil, fmt.Errorf("no key fields found for update in '%s'", resolveInfo.TableID.TableID())
+		}
+		reloadWhere = map[string]any{}
+		var updateFieldNames []string
+		for _, fn := range fieldNames {
+			if slices.Contains(resolveInfo.UpdateKeyFields, fn) {
+				continue
+			}
+			updateFieldNames = append(updateFieldNames, sch.LookUpField(fn).DBName)
+		}
+		for _, fn := range resolveInfo.UpdateKeyFields {
+			fv, ok := fields[fn]
+			if !ok {
+				return nil, fmt.Errorf("field %s is not set", fn)
+			}
+			reloadWhere[sch.LookUpField(fn).DBName] = fv
+		}
+		if len(updateFieldNames) > 0 {
+			err := tx.Model(model.Interface()).Select(updateFieldNames).Where(reloadWhere).
+				Updates(model.Interface()).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown resolve type: %v", resolveInfo.Type)
+	}
+
+	// reload the fields which were not generated by the database, and so were not returned.
+	var reloadFields []string
+	for _, fn := range returnFieldNames {
+		field := sch.LookUpField(fn)
+		if resolveInfo.Type == debefix.ResolveTypeAdd && slices.Contains(sch.FieldsWithDefaultDBValue, field) {
+			continue
+		}
+		reloadFields = append(reloadFields, field.DBName)
+	}
+	if len(reloadFields) > 0 {
+		if len(reloadWhere) == 0 {
+			return nil, fmt.Errorf("cannot reload fields for table '%s' without primary key values",
+				resolveInfo.TableID.TableID())
+		}
+		err := r.db.WithContext(ctx).Model(model.Interface()).Select(reloadFields).Where(reloadWhere).
+			Take(model.Interface()).Error
+		if err != nil {
+			return nil, fmt.Errorf("error reloading fields for table '%s': %w", resolveInfo.TableID.TableID(), err)
+		}
+	}
+
+	ret := map[string]any{}
+	for _, fn := range returnFieldNames {
+		ret[fn], _ = sch.LookUpField(fn).ValueOf(ctx, model.Elem())
+	}
+	return ret, nil
+}
+
+// schema returns the parsed GORM schema for the table model.
+func (r *resolver) schema(tableID debefix.TableID) (*schema.Schema, error) {
+	if model, ok := r.models[tableID.TableID()]; ok {
+		return r.parse(tableID, model)
+	}
+	for _, model := range r.tableModels {
+		sch, err := r.parse(tableID, model)
+		if err != nil {
+			return nil, err
+		}
+		if tableName := tableID.TableName(); sch.Table == tableName || sch.Table == unqualifiedTableName(tableName) {
+			return sch, nil
+		}
+	}
+	return nil, fmt.Errorf("no model registered for table '%s'", tableID.TableID())
+}
+
+func (r *resolver) parse(tableID debefix.TableID, model any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, fmt.Errorf("error parsing model for table '%s': %w", tableID.TableID(), err)
+	}
+	return stmt.Schema, nil
+}
+
+// unqualifiedTableName returns the table name without the schema.
+func unqualifiedTableName(tableName string) string {
+	if _, name, ok := strings.Cut(tableName, "."); ok {
+		return name
+	}
+	return tableName
+}
@@ -0,0 +1,173 @@
+package gorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/rrgmc/debefix/v2"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags  = debefix.TableName("tags")
+	tablePosts = debefix.TableName("public.posts")
+)
+
+type Tag struct {
+	TagID int64          `gorm:"primaryKey"`
+	Name  string         `gorm:"not null"`
+	Meta  map[string]any `gorm:"serializer:json"`
+}
+
+type Post struct {
+	PostID int64 `gorm:"primaryKey;autoIncrement:false"`
+	TagID  int64
+	Title  string
+	Slug   string
+	Status string `gorm:"default:draft"`
+}
+
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	p.Slug = strings.ToLower(strings.ReplaceAll(p.Title, " ", "-"))
+	return nil
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NilError(t, err)
+	assert.NilError(t, gdb.AutoMigrate(&Tag{}, &Post{}))
+	return gdb
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	gdb := openDB(t)
+
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("all"),
+			"name":   "All",
+			"meta":   map[string]any{"color": "red"},
+		},
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("half"),
+			"name":   "Half",
+		},
+	)
+
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "half", "tag_id"),
+			"title":   "First Post",
+			"status":  debefix.ResolveValueResolve(),
+		},
+	)
+
+	resolved, err := debefix.Resolve(ctx, data, ResolveFunc(gdb,
+		WithModels(&Tag{}),
+		WithModel(tablePosts, &Post{})))
+	assert.NilError(t, err)
+
+	var tags []Tag
+	assert.NilError(t, gdb.Order("tag_id").Find(&tags).Error)
+	assert.DeepEqual(t, []Tag{
+		{TagID: 1, Name: "All", Meta: map[string]any{"color": "red"}},
+		{TagID: 2, Name: "Half"},
+	}, tags)
+
+	var posts []Post
+	assert.NilError(t, gdb.Find(&posts).Error)
+	assert.DeepEqual(t, []Post{
+		{PostID: 1, TagID: 2, Title: "First Post", Slug: "first-post", Status: "draft"},
+	}, posts)
+
+	postStatus, err := resolved.FindTableRowValue(tablePosts, "status", func(row *debefix.Row) (bool, error) {
+		return true, nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, "draft", postStatus)
+}
+
+func TestResolveUpdate(t *testing.T) {
+	ctx := context.Background()
+	gdb := openDB(t)
+
+	data := debefix.NewData()
+
+	postIID := data.AddWithID(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"title":   "First Post",
+		},
+	)
+
+	data.Update(postIID.UpdateQuery([]string{"post_id"}),
+		debefix.UpdateActionSetValues{Values: debefix.MapValues{
+			"title": "First Post Updated",
+		}})
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(gdb, WithModel(tablePosts, &Post{})))
+	assert.NilError(t, err)
+
+	var posts []Post
+	assert.NilError(t, gdb.Find(&posts).Error)
+	assert.DeepEqual(t, []Post{
+		{PostID: 1, Title: "First Post Updated", Slug: "first-post", Status: "draft"},
+	}, posts)
+}
+
+func TestResolveZeroDefault(t *testing.T) {
+	ctx := context.Background()
+	gdb := openDB(t)
+
+	data := debefix.NewData()
+
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"title":   "First Post",
+			"status":  "",
+		},
+		debefix.MapValues{
+			"post_id": 2,
+			"title":   "Second Post",
+			"status":  debefix.ResolveValueResolve(),
+		},
+	)
+
+	resolved, err := debefix.Resolve(ctx, data, ResolveFunc(gdb, WithModels(&Tag{}, &Post{})))
+	assert.NilError(t, err)
+
+	var posts []Post
+	assert.NilError(t, gdb.Order("post_id").Find(&posts).Error)
+	assert.DeepEqual(t, []Post{
+		{PostID: 1, Title: "First Post", Slug: "first-post", Status: ""},
+		{PostID: 2, Title: "Second Post", Slug: "second-post", Status: "draft"},
+	}, posts)
+
+	postStatus, err := resolved.FindTableRowValue(tablePosts, "status", func(row *debefix.Row) (bool, error) {
+		postID, _ := row.Values.Get("post_id")
+		return postID == 2, nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, "draft", postStatus)
+}
+
+func TestResolveUnknownTable(t *testing.T) {
+	ctx := context.Background()
+	gdb := openDB(t)
+
+	data := debefix.NewData()
+	data.Add(debefix.TableName("users"), debefix.MapValues{"user_id": 1})
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(gdb, WithModels(&Tag{})))
+	assert.ErrorContains(t, err, "no model registered for table 'users'")
+}
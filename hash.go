@@ -0,0 +1,196 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// DataHash returns a hash of the tables, rows and updates of the data, which only changes if the data changes.
+// The row internal IDs, which are randomly generated, are replaced by the row position, and functions are hashed
+// by their type only, so changes in the behavior of functions don't change the hash; callers storing the hash
+// should also store a version which changes with them.
+// Values generated when the data is built, like debefix.ValueUUIDRandom, change the hash on each build.
+func DataHash(data *debefix.Data) (string, error) {
+	if data.Err() != nil {
+		return "", data.Err()
+	}
+
+	sum := sha256.New()
+	h := &dataHasher{
+		w:           sum,
+		internalIDs: map[uuid.UUID]string{},
+		visited:     map[uintptr]bool{},
+	}
+
+	tableIDs := slices.Sorted(maps.Keys(data.Tables))
+
+	for _, tableID := range tableIDs {
+		for rowIdx, row := range data.Tables[tableID].Rows {
+			h.internalIDs[row.InternalID] = fmt.Sprintf("%s#%d", tableID, rowIdx)
+		}
+	}
+
+	for _, tableID := range tableIDs {
+		table := data.Tables[tableID]
+		h.writef("table %s\n", tableID)
+		var depends []string
+		for _, dep := range table.Depends {
+			depends = append(depends, dep.TableID())
+		}
+		slices.Sort(depends)
+		h.writef("depends %s\n", strings.Join(depends, ","))
+
+		for rowIdx, row := range table.Rows {
+			h.writef("row %d refid %q\n", rowIdx, row.RefID)
+			values := map[string]any{}
+			for fn, fv := range row.Values.All {
+				values[fn] = fv
+			}
+			for _, fn := range slices.Sorted(maps.Keys(values)) {
+				h.writef("field %q ", fn)
+				h.writeValue(reflect.ValueOf(values[fn]))
+				h.writef("\n")
+			}
+			for _, update := range row.Updates {
+				h.writef("row update ")
+				h.writeValue(reflect.ValueOf(update))
+				h.writef("\n")
+			}
+			h.writef("resolved callbacks %d\n", len(row.ResolvedCallbacks))
+		}
+	}
+
+	for _, update := range data.Updates {
+		h.writef("update ")
+		h.writeValue(reflect.ValueOf(update))
+		h.writef("\n")
+	}
+
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+var (
+	uuidType = reflect.TypeFor[uuid.UUID]()
+	timeType = reflect.TypeFor[time.Time]()
+)
+
+type dataHasher struct {
+	w           io.Writer
+	internalIDs map[uuid.UUID]string
+	visited     map[uintptr]bool
+}
+
+func (h *dataHasher) writef(format string, args ...any) {
+	_, _ = fmt.Fprintf(h.w, format, args...)
+}
+
+func (h *dataHasher) writeValue(v reflect.Value) {
+	if !v.IsValid() {
+		_, _ = io.WriteString(h.w, "nil")
+		return
+	}
+
+	switch {
+	case v.Type() == uuidType:
+		var u uuid.UUID
+		for i := range u {
+			u[i] = byte(v.Index(i).Uint())
+		}
+		if label, ok := h.internalIDs[u]; ok {
+			h.writef("internalid(%s)", label)
+		} else {
+			h.writef("uuid(%s)", u)
+		}
+		return
+	case v.Type() == timeType && v.CanInterface():
+		h.writef("time(%s)", v.Interface().(time.Time).Format(time.RFC3339Nano))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		h.writef("%t", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.writef("%s(%d)", v.Type(), v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.writef("%s(%d)", v.Type(), v.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.writef("%s(%v)", v.Type(), v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		h.writef("%s(%v)", v.Type(), v.Complex())
+	case reflect.String:
+		h.writef("%s(%q)", v.Type(), v.String())
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		h.writef("%s", v.Type())
+	case reflect.Interface:
+		if v.IsNil() {
+			_, _ = io.WriteString(h.w, "nil")
+			return
+		}
+		h.writeValue(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			_, _ = io.WriteString(h.w, "nil")
+			return
+		}
+		if h.visited[v.Pointer()] {
+			h.writef("cycle(%s)", v.Type())
+			return
+		}
+		h.visited[v.Pointer()] = true
+		defer delete(h.visited, v.Pointer())
+		h.writef("&")
+		h.writeValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		h.writef("%s{", v.Type())
+		for i := 0; i < v.Len(); i++ {
+			h.writeValue(v.Index(i))
+			h.writef(",")
+		}
+		h.writef("}")
+	case reflect.Map:
+		// map keys are sorted by their hashed representation.
+		type entry struct {
+			key   string
+			value reflect.Value
+		}
+		var entries []entry
+		iter := v.MapRange()
+		for iter.Next() {
+			var key strings.Builder
+			kh := &dataHasher{w: &key, internalIDs: h.internalIDs, visited: h.visited}
+			kh.writeValue(iter.Key())
+			entries = append(entries, entry{key: key.String(), value: iter.Value()})
+		}
+		slices.SortFunc(entries, func(a, b entry) int {
+			return strings.Compare(a.key, b.key)
+		})
+		h.writef("%s{", v.Type())
+		for _, e := range entries {
+			h.writef("%s:", e.key)
+			h.writeValue(e.value)
+			h.writef(",")
+		}
+		h.writef("}")
+	case reflect.Struct:
+		h.writef("%s{", v.Type())
+		for i := 0; i < v.NumField(); i++ {
+			h.writef("%s:", v.Type().Field(i).Name)
+			h.writeValue(v.Field(i))
+			h.writef(",")
+		}
+		h.writef("}")
+	default:
+		h.writef("%s", v.Type())
+	}
+}
@@ -0,0 +1,494 @@
+// Package dbmock is a minimal [database/sql] driver for tests, which checks the executed statements against a list
+// of expectations, in the style of github.com/DATA-DOG/go-sqlmock, without adding dependencies to the module.
+package dbmock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const driverName = "dbmock"
+
+var (
+	registerOnce sync.Once
+	mocksMu      sync.Mutex
+	mocks        = map[string]*Mock{}
+	dsnSeq       int
+)
+
+// New creates a database connection pool and the Mock which checks its statements.
+func New() (*sql.DB, *Mock, error) {
+	mocksMu.Lock()
+	dsnSeq++
+	dsn := fmt.Sprintf("dbmock_%d", dsnSeq)
+	mocksMu.Unlock()
+	return NewWithDSN(dsn)
+}
+
+// NewWithDSN is like New, but the connection is registered with dsn, so other connections to the same mock can be
+// opened with sql.Open("dbmock", dsn).
+func NewWithDSN(dsn string) (*sql.DB, *Mock, error) {
+	registerOnce.Do(func() {
+		sql.Register(driverName, mockDriver{})
+	})
+
+	mock := &Mock{ordered: true}
+	mocksMu.Lock()
+	if _, ok := mocks[dsn]; ok {
+		mocksMu.Unlock()
+		return nil, nil, fmt.Errorf("dsn '%s' already registered", dsn)
+	}
+	mocks[dsn] = mock
+	mocksMu.Unlock()
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, mock, nil
+}
+
+// Mock holds the expected statements of a connection.
+type Mock struct {
+	mu       sync.Mutex
+	ordered  bool
+	expected []*expectation
+}
+
+// MatchExpectationsInOrder sets whether the statements must be executed in the order they were expected. The
+// default is true.
+func (m *Mock) MatchExpectationsInOrder(ordered bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ordered = ordered
+}
+
+// ExpectExec expects an Exec with a query matching the regular expression.
+func (m *Mock) ExpectExec(queryRe string) *ExpectedExec {
+	return &ExpectedExec{e: m.expect(kindExec, queryRe)}
+}
+
+// ExpectQuery expects a Query with a query matching the regular expression.
+func (m *Mock) ExpectQuery(queryRe string) *ExpectedQuery {
+	return &ExpectedQuery{e: m.expect(kindQuery, queryRe)}
+}
+
+// ExpectBegin expects the start of a transaction.
+func (m *Mock) ExpectBegin() *ExpectedTx {
+	return &ExpectedTx{e: m.expect(kindBegin, "")}
+}
+
+// ExpectCommit expects a transaction commit.
+func (m *Mock) ExpectCommit() *ExpectedTx {
+	return &ExpectedTx{e: m.expect(kindCommit, "")}
+}
+
+// ExpectRollback expects a transaction rollback.
+func (m *Mock) ExpectRollback() *ExpectedTx {
+	return &ExpectedTx{e: m.expect(kindRollback, "")}
+}
+
+// ExpectationsWereMet returns an error if any expectation was not triggered.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for _, e := range m.expected {
+		if !e.triggered {
+			errs = append(errs, fmt.Errorf("expectation not met: %s", e))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Mock) expect(kind expectationKind, queryRe string) *expectation {
+	e := &expectation{kind: kind}
+	if kind == kindExec || kind == kindQuery {
+		e.re = regexp.MustCompile(stripQuery(queryRe))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expected = append(m.expected, e)
+	return e
+}
+
+// match finds the expectation for a call and marks it as triggered.
+func (m *Mock) match(kind expectationKind, query string, args []driver.NamedValue) (*expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = stripQuery(query)
+	for _, e := range m.expected {
+		if e.triggered {
+			continue
+		}
+		err := e.matches(kind, query, args)
+		if err == nil {
+			e.triggered = true
+			return e, e.err
+		}
+		if m.ordered {
+			return nil, fmt.Errorf("call to %s was not expected, next expectation is %s: %w",
+				callString(kind, query, args), e, err)
+		}
+	}
+	return nil, fmt.Errorf("call to %s was not expected", callString(kind, query, args))
+}
+
+type expectationKind string
+
+const (
+	kindExec     expectationKind = "exec"
+	kindQuery    expectationKind = "query"
+	kindBegin    expectationKind = "begin"
+	kindCommit   expectationKind = "commit"
+	kindRollback expectationKind = "rollback"
+)
+
+type expectation struct {
+	kind      expectationKind
+	re        *regexp.Regexp
+	args      []any
+	argsSet   bool
+	result    driver.Result
+	rows      *Rows
+	err       error
+	triggered bool
+}
+
+func (e *expectation) String() string {
+	if e.re == nil {
+		return string(e.kind)
+	}
+	if e.argsSet {
+		return fmt.Sprintf("%s `%s` with args %v", e.kind, e.re, e.args)
+	}
+	return fmt.Sprintf("%s `%s`", e.kind, e.re)
+}
+
+func (e *expectation) matches(kind expectationKind, query string, args []driver.NamedValue) error {
+	if e.kind != kind {
+		return fmt.Errorf("expected %s", e.kind)
+	}
+	if e.re == nil {
+		return nil
+	}
+	if !e.re.MatchString(query) {
+		return fmt.Errorf("query does not match `%s`", e.re)
+	}
+	if !e.argsSet {
+		return nil
+	}
+	if len(args) != len(e.args) {
+		return fmt.Errorf("expected %d arguments, got %d", len(e.args), len(args))
+	}
+	for i, arg := range args {
+		actual := normalizeValue(arg.Value)
+		if matcher, ok := e.args[i].(Argument); ok {
+			if !matcher.Match(actual) {
+				return fmt.Errorf("argument %d does not match", i+1)
+			}
+			continue
+		}
+		expected := e.args[i]
+		if named, ok := expected.(sql.NamedArg); ok {
+			if named.Name != arg.Name {
+				return fmt.Errorf("expected argument %d name '%s', got '%s'", i+1, named.Name, arg.Name)
+			}
+			expected = named.Value
+		}
+		if !reflect.DeepEqual(normalizeValue(expected), actual) {
+			return fmt.Errorf("expected argument %d to be %#v, got %#v", i+1, expected, arg.Value)
+		}
+	}
+	return nil
+}
+
+// ExpectedExec is an expected Exec.
+type ExpectedExec struct {
+	e *expectation
+}
+
+// WithArgs sets the expected arguments. Argument values are matched using their Match method.
+func (e *ExpectedExec) WithArgs(args ...any) *ExpectedExec {
+	e.e.args, e.e.argsSet = args, true
+	return e
+}
+
+// WillReturnResult sets the result of the Exec.
+func (e *ExpectedExec) WillReturnResult(result driver.Result) *ExpectedExec {
+	e.e.result = result
+	return e
+}
+
+// WillReturnError sets the error of the Exec.
+func (e *ExpectedExec) WillReturnError(err error) *ExpectedExec {
+	e.e.err = err
+	return e
+}
+
+// ExpectedQuery is an expected Query.
+type ExpectedQuery struct {
+	e *expectation
+}
+
+// WithArgs sets the expected arguments. Argument values are matched using their Match method.
+func (e *ExpectedQuery) WithArgs(args ...any) *ExpectedQuery {
+	e.e.args, e.e.argsSet = args, true
+	return e
+}
+
+// WillReturnRows sets the rows returned by the Query.
+func (e *ExpectedQuery) WillReturnRows(rows *Rows) *ExpectedQuery {
+	e.e.rows = rows
+	return e
+}
+
+// WillReturnError sets the error of the Query.
+func (e *ExpectedQuery) WillReturnError(err error) *ExpectedQuery {
+	e.e.err = err
+	return e
+}
+
+// ExpectedTx is an expected transaction operation.
+type ExpectedTx struct {
+	e *expectation
+}
+
+// WillReturnError sets the error of the operation.
+func (e *ExpectedTx) WillReturnError(err error) *ExpectedTx {
+	e.e.err = err
+	return e
+}
+
+// Argument matches an argument value.
+type Argument interface {
+	Match(value driver.Value) bool
+}
+
+// AnyArg returns an Argument which matches any value.
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(driver.Value) bool { return true }
+
+// NewResult returns an Exec result.
+func NewResult(lastInsertID int64, rowsAffected int64) driver.Result {
+	return result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+}
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// Rows are the rows returned by a Query.
+type Rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// NewRows creates Rows with the columns.
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow adds a row, with one value for each column.
+func (r *Rows) AddRow(values ...driver.Value) *Rows {
+	if len(values) != len(r.columns) {
+		panic(fmt.Sprintf("expected %d row values, got %d", len(r.columns), len(values)))
+	}
+	r.values = append(r.values, values)
+	return r
+}
+
+// normalizeValue converts a value to the driver value types, so equivalent values can be compared.
+func normalizeValue(value any) any {
+	if v, err := driver.DefaultParameterConverter.ConvertValue(value); err == nil {
+		return v
+	}
+	return value
+}
+
+var whitespaceRe = regexp.MustCompile(`\s+`)
+
+func stripQuery(query string) string {
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(query, " "))
+}
+
+func callString(kind expectationKind, query string, args []driver.NamedValue) string {
+	if kind != kindExec && kind != kindQuery {
+		return string(kind)
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return fmt.Sprintf("%s `%s` with args %v", kind, query, values)
+}
+
+type mockDriver struct{}
+
+func (mockDriver) Open(dsn string) (driver.Conn, error) {
+	mocksMu.Lock()
+	defer mocksMu.Unlock()
+	mock, ok := mocks[dsn]
+	if !ok {
+		return nil, fmt.Errorf("no mock registered for dsn '%s'", dsn)
+	}
+	return &conn{mock: mock}, nil
+}
+
+type conn struct {
+	mock *Mock
+}
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a statement which checks the expectations when executed.
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.mock.match(kindBegin, "", nil); err != nil {
+		return nil, err
+	}
+	return tx{conn: c}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.mock.match(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return nil, fmt.Errorf("no result set for %s", e)
+	}
+	return e.result, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.mock.match(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.rows == nil {
+		return nil, fmt.Errorf("no rows set for %s", e)
+	}
+	return &rows{rows: e.rows}, nil
+}
+
+// CheckNamedValue accepts all argument types, so they are compared without conversion.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func (s *stmt) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func valuesToNamed(values []driver.Value) []driver.NamedValue {
+	ret := make([]driver.NamedValue, len(values))
+	for i, v := range values {
+		ret[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return ret
+}
+
+type tx struct {
+	conn *conn
+}
+
+func (t tx) Commit() error {
+	_, err := t.conn.mock.match(kindCommit, "", nil)
+	return err
+}
+
+func (t tx) Rollback() error {
+	_, err := t.conn.mock.match(kindRollback, "", nil)
+	return err
+}
+
+type rows struct {
+	rows *Rows
+	pos  int
+}
+
+func (r *rows) Columns() []string {
+	return r.rows.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows.values) {
+		return io.EOF
+	}
+	copy(dest, r.rows.values[r.pos])
+	r.pos++
+	return nil
+}
@@ -0,0 +1,26 @@
+module github.com/rrgmc/debefix-db/metrics/v2
+
+go 1.23
+
+require (
+	github.com/prometheus/client_golang v1.20.5
+	github.com/rrgmc/debefix-db/v2 v2.1.0
+	github.com/rrgmc/debefix/v2 v2.0.6
+	gotest.tools/v3 v3.5.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+replace github.com/rrgmc/debefix-db/v2 => ..
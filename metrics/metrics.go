@@ -0,0 +1,260 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Metrics collects statistics of resolves. ResolveDBMiddleware counts the inserted and updated rows, and
+// QueryInterfaceMiddleware counts the executed statements, returned fields and their latency.
+// Errors are counted by kind, only once when both middlewares are used. The statistics are available using Stats, and Metrics is also a
+// [prometheus.Collector].
+type Metrics struct {
+	namespace      string
+	buckets        []float64
+	errorKind      func(err error) string
+	rowsDesc       *prometheus.Desc
+	statementsDesc *prometheus.Desc
+	returnedDesc   *prometheus.Desc
+	errorsDesc     *prometheus.Desc
+	latencyDesc    *prometheus.Desc
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// Stats are the collected statistics.
+type Stats struct {
+	Tables         map[string]TableStats // statistics by table ID.
+	Statements     int64
+	ReturnedFields int64
+	Errors         map[string]int64 // error count by kind.
+	Latency        Histogram        // statement latency.
+}
+
+// TableStats are the statistics of a table.
+type TableStats struct {
+	Inserted   int64
+	Updated    int64
+	Statements int64
+}
+
+// Histogram is a latency histogram. Counts has the count of observations less than or equal to each bucket, in
+// seconds, like Prometheus histograms.
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Error kinds returned by the default error classifier.
+const (
+	ErrorKindCanceled = "canceled"
+	ErrorKindTimeout  = "timeout"
+	ErrorKindError    = "error"
+)
+
+// New creates a Metrics.
+func New(options ...Option) *Metrics {
+	ret := &Metrics{
+		namespace: "debefix",
+		buckets:   prometheus.DefBuckets,
+		errorKind: DefaultErrorKind,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	ret.stats = Stats{
+		Tables: map[string]TableStats{},
+		Errors: map[string]int64{},
+		Latency: Histogram{
+			Buckets: ret.buckets,
+			Counts:  make([]uint64, len(ret.buckets)),
+		},
+	}
+	ret.rowsDesc = prometheus.NewDesc(prometheus.BuildFQName(ret.namespace, "", "rows_total"),
+		"Number of resolved rows.", []string{"table", "type"}, nil)
+	ret.statementsDesc = prometheus.NewDesc(prometheus.BuildFQName(ret.namespace, "", "statements_total"),
+		"Number of executed statements.", []string{"table"}, nil)
+	ret.returnedDesc = prometheus.NewDesc(prometheus.BuildFQName(ret.namespace, "", "returned_fields_total"),
+		"Number of fields returned by the database.", nil, nil)
+	ret.errorsDesc = prometheus.NewDesc(prometheus.BuildFQName(ret.namespace, "", "errors_total"),
+		"Number of errors.", []string{"kind"}, nil)
+	ret.latencyDesc = prometheus.NewDesc(prometheus.BuildFQName(ret.namespace, "", "statement_duration_seconds"),
+		"Statement execution latency.", nil, nil)
+	return ret
+}
+
+// Option is an option for New.
+type Option func(*Metrics)
+
+// WithNamespace sets the namespace of the Prometheus metrics. The default is "debefix".
+func WithNamespace(namespace string) Option {
+	return func(m *Metrics) {
+		m.namespace = namespace
+	}
+}
+
+// WithBuckets sets the latency histogram buckets, in seconds. The default is [prometheus.DefBuckets].
+func WithBuckets(buckets ...float64) Option {
+	return func(m *Metrics) {
+		m.buckets = slices.Sorted(slices.Values(buckets))
+	}
+}
+
+// WithErrorKind sets the function which returns the kind of error. The default is DefaultErrorKind.
+func WithErrorKind(errorKind func(err error) string) Option {
+	return func(m *Metrics) {
+		m.errorKind = errorKind
+	}
+}
+
+// DefaultErrorKind returns ErrorKindCanceled for canceled contexts, ErrorKindTimeout for expired deadlines and
+// ErrorKindError for all other errors.
+func DefaultErrorKind(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorKindTimeout
+	default:
+		return ErrorKindError
+	}
+}
+
+// ResolveDBMiddleware returns a db.ResolveDBMiddleware which counts the inserted and updated rows per table, and
+// the errors.
+func (m *Metrics) ResolveDBMiddleware() db.ResolveDBMiddleware {
+	return func(next db.ResolveDBCallback) db.ResolveDBCallback {
+		return func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+			returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			ret, err := next(context.WithValue(ctx, errorsCountedContextKey{m: m}, true), resolveInfo, fields,
+				returnFields)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if err != nil {
+				m.stats.Errors[m.errorKind(err)]++
+				return ret, err
+			}
+			ts := m.stats.Tables[resolveInfo.TableID.TableID()]
+			switch resolveInfo.Type {
+			case debefix.ResolveTypeAdd:
+				ts.Inserted++
+			case debefix.ResolveTypeUpdate:
+				ts.Updated++
+			}
+			m.stats.Tables[resolveInfo.TableID.TableID()] = ts
+			return ret, nil
+		}
+	}
+}
+
+// QueryInterfaceMiddleware returns a sql.QueryInterfaceMiddleware which counts the executed statements per table,
+// the returned fields and the statement latency. It also counts the errors, unless the statement is executed
+// inside ResolveDBMiddleware, which already counts them.
+func (m *Metrics) QueryInterfaceMiddleware() sql.QueryInterfaceMiddleware {
+	return func(next sql.QueryInterface) sql.QueryInterface {
+		return sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+			returnFieldNames []string, args ...any) (map[string]any, error) {
+			start := time.Now()
+			ret, err := next.Query(ctx, tableID, query, returnFieldNames, args...)
+			duration := time.Since(start)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.stats.Statements++
+			ts := m.stats.Tables[tableID.TableID()]
+			ts.Statements++
+			m.stats.Tables[tableID.TableID()] = ts
+			m.stats.ReturnedFields += int64(len(ret))
+			m.stats.Latency.observe(duration)
+			if err != nil && ctx.Value(errorsCountedContextKey{m: m}) == nil {
+				m.stats.Errors[m.errorKind(err)]++
+			}
+			return ret, err
+		})
+	}
+}
+
+// ResolveDBFunc returns a db.ResolveDBCallback using both middlewares.
+func (m *Metrics) ResolveDBFunc(qi sql.QueryInterface, queryBuilder sql.QueryBuilder) db.ResolveDBCallback {
+	return m.ResolveDBMiddleware()(sql.ResolveDBFunc(m.QueryInterfaceMiddleware()(qi), queryBuilder))
+}
+
+// ResolveFunc returns a debefix.ResolveCallback using both middlewares.
+func (m *Metrics) ResolveFunc(qi sql.QueryInterface, queryBuilder sql.QueryBuilder) debefix.ResolveCallback {
+	return db.ResolveFunc(m.ResolveDBFunc(qi, queryBuilder))
+}
+
+// Stats returns a copy of the collected statistics.
+func (m *Metrics) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Stats{
+		Tables:         maps.Clone(m.stats.Tables),
+		Statements:     m.stats.Statements,
+		ReturnedFields: m.stats.ReturnedFields,
+		Errors:         maps.Clone(m.stats.Errors),
+		Latency: Histogram{
+			Buckets: slices.Clone(m.stats.Latency.Buckets),
+			Counts:  slices.Clone(m.stats.Latency.Counts),
+			Count:   m.stats.Latency.Count,
+			Sum:     m.stats.Latency.Sum,
+		},
+	}
+}
+
+// errorsCountedContextKey is set by ResolveDBMiddleware, so QueryInterfaceMiddleware of the same Metrics don't count
+// the errors again.
+type errorsCountedContextKey struct {
+	m *Metrics
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.rowsDesc
+	ch <- m.statementsDesc
+	ch <- m.returnedDesc
+	ch <- m.errorsDesc
+	ch <- m.latencyDesc
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	stats := m.Stats()
+	for table, ts := range stats.Tables {
+		ch <- prometheus.MustNewConstMetric(m.rowsDesc, prometheus.CounterValue, float64(ts.Inserted), table, "insert")
+		ch <- prometheus.MustNewConstMetric(m.rowsDesc, prometheus.CounterValue, float64(ts.Updated), table, "update")
+		ch <- prometheus.MustNewConstMetric(m.statementsDesc, prometheus.CounterValue, float64(ts.Statements), table)
+	}
+	ch <- prometheus.MustNewConstMetric(m.returnedDesc, prometheus.CounterValue, float64(stats.ReturnedFields))
+	for kind, count := range stats.Errors {
+		ch <- prometheus.MustNewConstMetric(m.errorsDesc, prometheus.CounterValue, float64(count), kind)
+	}
+	buckets := map[float64]uint64{}
+	for i, b := range stats.Latency.Buckets {
+		buckets[b] = stats.Latency.Counts[i]
+	}
+	ch <- prometheus.MustNewConstHistogram(m.latencyDesc, stats.Latency.Count, stats.Latency.Sum.Seconds(), buckets)
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	h.Count++
+	h.Sum += d
+	for i, b := range h.Buckets {
+		if d.Seconds() <= b {
+			h.Counts[i]++
+		}
+	}
+}
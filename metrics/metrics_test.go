@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	tableTags := debefix.TableName("public.tags")
+	tablePosts := debefix.TableName("public.posts")
+
+	qi := sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		if strings.HasPrefix(query, "UPDATE public.posts") {
+			return nil, context.DeadlineExceeded
+		}
+		if len(returnFieldNames) > 0 {
+			return map[string]any{"tag_id": 10}, nil
+		}
+		return nil, nil
+	})
+
+	data := debefix.NewData()
+	tagIID := data.AddWithID(tableTags,
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("go"),
+			"name":   "Go",
+		})
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": 2,
+			"name":   "JavaScript",
+		},
+	)
+	data.Update(tagIID.UpdateQuery([]string{"tag_id"}), debefix.UpdateActionSetValues{
+		Values: debefix.MapValues{"name": "Golang"},
+	})
+	postIID := data.AddWithID(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "go", "tag_id"),
+		})
+	data.Update(postIID.UpdateQuery([]string{"post_id"}), debefix.UpdateActionSetValues{
+		Values: debefix.MapValues{"tag_id": 2},
+	})
+
+	m := New(WithBuckets(10, 1))
+
+	_, err := debefix.Resolve(ctx, data, m.ResolveFunc(qi, sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{})))
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+
+	stats := m.Stats()
+	assert.DeepEqual(t, map[string]TableStats{
+		"public.tags":  {Inserted: 2, Updated: 1, Statements: 3},
+		"public.posts": {Inserted: 1, Statements: 2},
+	}, stats.Tables)
+	assert.Equal(t, int64(5), stats.Statements)
+	assert.Equal(t, int64(1), stats.ReturnedFields)
+	assert.DeepEqual(t, map[string]int64{ErrorKindTimeout: 1}, stats.Errors)
+	assert.DeepEqual(t, []float64{1, 10}, stats.Latency.Buckets)
+	assert.DeepEqual(t, []uint64{5, 5}, stats.Latency.Counts)
+	assert.Equal(t, uint64(5), stats.Latency.Count)
+
+	err = testutil.CollectAndCompare(m, strings.NewReader(`
+# HELP debefix_errors_total Number of errors.
+# TYPE debefix_errors_total counter
+debefix_errors_total{kind="timeout"} 1
+# HELP debefix_returned_fields_total Number of fields returned by the database.
+# TYPE debefix_returned_fields_total counter
+debefix_returned_fields_total 1
+# HELP debefix_rows_total Number of resolved rows.
+# TYPE debefix_rows_total counter
+debefix_rows_total{table="public.posts",type="insert"} 1
+debefix_rows_total{table="public.posts",type="update"} 0
+debefix_rows_total{table="public.tags",type="insert"} 2
+debefix_rows_total{table="public.tags",type="update"} 1
+# HELP debefix_statements_total Number of executed statements.
+# TYPE debefix_statements_total counter
+debefix_statements_total{table="public.posts"} 2
+debefix_statements_total{table="public.tags"} 3
+`), "debefix_errors_total", "debefix_returned_fields_total", "debefix_rows_total", "debefix_statements_total")
+	assert.NilError(t, err)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m, "debefix_statement_duration_seconds"))
+}
+
+func TestMetricsErrorsCountedOnce(t *testing.T) {
+	ctx := context.Background()
+
+	tableTags := debefix.TableName("public.tags")
+
+	qi := sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		return nil, errors.New("insert failed")
+	})
+
+	data := debefix.NewData()
+	data.Add(tableTags, debefix.MapValues{"tag_id": 1})
+
+	m := New()
+	_, err := debefix.Resolve(ctx, data, db.ResolveFunc(m.ResolveDBMiddleware()(
+		sql.ResolveDBFunc(m.QueryInterfaceMiddleware()(qi), sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{})))))
+	assert.ErrorContains(t, err, "insert failed")
+	assert.DeepEqual(t, map[string]int64{ErrorKindError: 1}, m.Stats().Errors)
+
+	m = New()
+	_, err = debefix.Resolve(ctx, data, sql.ResolveFunc(m.QueryInterfaceMiddleware()(qi),
+		sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{})))
+	assert.ErrorContains(t, err, "insert failed")
+	assert.DeepEqual(t, map[string]int64{ErrorKindError: 1}, m.Stats().Errors)
+}
@@ -0,0 +1,15 @@
+package db
+
+// ResolveDBMiddleware is a function that wraps a ResolveDBCallback, to add behavior like logging, timing or
+// retries.
+type ResolveDBMiddleware func(next ResolveDBCallback) ResolveDBCallback
+
+// Chain returns a ResolveDBMiddleware which applies the middlewares in order, the first one being the outermost.
+func Chain(middlewares ...ResolveDBMiddleware) ResolveDBMiddleware {
+	return func(next ResolveDBCallback) ResolveDBCallback {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
@@ -0,0 +1,226 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Values converts a struct (or a pointer to a struct) with "db" tags to [debefix.MapValues].
+//
+// The tag format is `db:"name[,option...]"`, where options can be:
+//   - omitempty: the field is not added if it has the zero value.
+//   - generated: the field is generated by the database. If it has the zero value, it is set as
+//     [debefix.ResolveValueResolve], so it will be returned by the resolver.
+//
+// Fields tagged with `db:"-"` and unexported fields are ignored. Fields without a tag use the lowercase field name.
+// Embedded structs without a tag name have their fields added to the parent. Embedded pointers to structs must be
+// exported; if nil, their fields have the zero value, and they are allocated by Scan.
+func Values(v any, options ...Option) (debefix.MapValues, error) {
+	var optns modelOptions
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := debefix.MapValues{}
+	for _, field := range structFields(rv.Type()) {
+		fv, err := rv.FieldByIndexErr(field.index)
+		if err != nil {
+			// nil embedded struct pointer.
+			fv = reflect.Zero(field.typ)
+		}
+		switch {
+		case field.generated && fv.IsZero():
+			ret[field.name] = debefix.ResolveValueResolve()
+		case field.omitEmpty && fv.IsZero():
+		default:
+			ret[field.name] = fv.Interface()
+		}
+	}
+
+	if optns.refID != "" {
+		ret["_refid"] = debefix.SetValueRefID(optns.refID)
+	}
+	for fn, fv := range optns.fieldValues {
+		ret[fn] = fv
+	}
+
+	return ret, nil
+}
+
+// Add adds the struct pointed by v as a row of the table, using Values to convert it.
+// After the row is resolved, the struct is populated with the resolved values using Scan, so fields generated by
+// the database are set.
+func Add(data *debefix.Data, tableID debefix.TableID, v any, options ...Option) (debefix.InternalIDRef, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return debefix.InternalIDRef{}, fmt.Errorf("value must be a non-nil pointer to a struct, got %T", v)
+	}
+
+	values, err := Values(v, options...)
+	if err != nil {
+		return debefix.InternalIDRef{}, err
+	}
+
+	return data.AddWithID(tableID, values,
+		debefix.WithDataAddResolvedCallback(func(ctx context.Context, resolvedData *debefix.ResolvedData,
+			resolveInfo debefix.ResolveInfo, resolvedRow *debefix.Row) error {
+			return Scan(resolvedRow.Values, v)
+		})), nil
+}
+
+// Scan sets the fields of the struct pointed by dest from values, using the same field mapping as Values.
+// Fields that don't exist in values are not changed.
+func Scan(values debefix.Values, dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+
+	for _, field := range structFields(rv.Type()) {
+		value, ok := values.Get(field.name)
+		if !ok {
+			continue
+		}
+		if _, ok := value.(debefix.ResolveValue); ok {
+			continue
+		}
+		if err := assignValue(fieldByIndexAlloc(rv, field.index), value); err != nil {
+			return fmt.Errorf("error setting field '%s': %w", field.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Option is an option for Values and Add.
+type Option func(options *modelOptions)
+
+// WithRefID sets the RefID of the row.
+func WithRefID(refID debefix.RefID) Option {
+	return func(options *modelOptions) {
+		options.refID = refID
+	}
+}
+
+// WithFieldValue sets a field value, overriding the struct value. It can be used to set values which
+// can't be represented in the struct, like [debefix.ValueRefID].
+func WithFieldValue(fieldName string, value any) Option {
+	return func(options *modelOptions) {
+		if options.fieldValues == nil {
+			options.fieldValues = map[string]any{}
+		}
+		options.fieldValues[fieldName] = value
+	}
+}
+
+type modelOptions struct {
+	refID       debefix.RefID
+	fieldValues map[string]any
+}
+
+// structField is a struct field mapped to a database field.
+type structField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	generated bool
+}
+
+// structValue returns the struct value of v, dereferencing pointers.
+func structValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}, errors.New("value cannot be a nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("value must be a struct or a pointer to a struct, got %T", v)
+	}
+	return rv, nil
+}
+
+// structFields returns the list of mapped fields of a struct type, including the ones of embedded structs.
+func structFields(t reflect.Type) []structField {
+	var ret []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, hasTag := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for _, ef := range structFields(f.Type) {
+				ef.index = append([]int{i}, ef.index...)
+				ret = append(ret, ef)
+			}
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Pointer && f.Type.Elem().Kind() == reflect.Struct {
+			if !f.IsExported() {
+				// can't be allocated by Scan.
+				continue
+			}
+			for _, ef := range structFields(f.Type.Elem()) {
+				ef.index = append([]int{i}, ef.index...)
+				ret = append(ret, ef)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if !hasTag || name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		field := structField{
+			name:  name,
+			index: []int{i},
+			typ:   f.Type,
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				field.omitEmpty = true
+			case "generated":
+				field.generated = true
+			}
+		}
+		ret = append(ret, field)
+	}
+	return ret
+}
+
+// fieldByIndexAlloc returns the nested field of v by index, allocating nil embedded struct pointers.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+var (
+	tableTags  = debefix.TableName("public.tags")
+	tablePosts = debefix.TableName("public.posts")
+)
+
+type timestamps struct {
+	CreatedAt time.Time `db:"created_at,generated"`
+	UpdatedAt time.Time `db:"updated_at,omitempty"`
+}
+
+type tag struct {
+	TagID int64          `db:"tag_id,generated"`
+	Name  string         `db:"tag_name"`
+	Color sql.NullString `db:"color,omitempty"`
+	Extra string         `db:"-"`
+	timestamps
+}
+
+type post struct {
+	PostID int
+	Title  string
+	TagID  *int64 `db:"tag_id"`
+}
+
+func TestValues(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	values, err := Values(&tag{
+		Name:  "All",
+		Extra: "extra",
+		timestamps: timestamps{
+			UpdatedAt: updatedAt,
+		},
+	}, WithRefID("all"))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, debefix.MapValues{
+		"tag_id":     debefix.ResolveValueResolve(),
+		"tag_name":   "All",
+		"created_at": debefix.ResolveValueResolve(),
+		"updated_at": updatedAt,
+		"_refid":     debefix.SetValueRefID("all"),
+	}, values)
+}
+
+func TestValuesInvalid(t *testing.T) {
+	_, err := Values(10)
+	assert.ErrorContains(t, err, "must be a struct")
+
+	var tv *tag
+	_, err = Values(tv)
+	assert.ErrorContains(t, err, "nil pointer")
+}
+
+func TestAdd(t *testing.T) {
+	ctx := context.Background()
+
+	data := debefix.NewData()
+
+	tagAll := &tag{Name: "All"}
+	_, err := Add(data, tableTags, tagAll, WithRefID("all"))
+	assert.NilError(t, err)
+
+	post1 := &post{PostID: 1, Title: "First post"}
+	_, err = Add(data, tablePosts, post1, WithFieldValue("tag_id", debefix.ValueRefID(tableTags, "all", "tag_id")))
+	assert.NilError(t, err)
+
+	createdAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tables := map[string][]map[string]any{}
+
+	_, err = debefix.Resolve(ctx, data,
+		db.ResolveFunc(func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+			returnFieldNames map[string]debefix.ResolveValue) (returnValues map[string]any, err error) {
+			tables[resolveInfo.TableID.TableID()] = append(tables[resolveInfo.TableID.TableID()], fields)
+			if resolveInfo.TableID.TableID() == tableTags.TableID() {
+				assert.Assert(t, is.Contains(returnFieldNames, "tag_id"))
+				assert.Assert(t, is.Contains(returnFieldNames, "created_at"))
+				return map[string]any{
+					"tag_id":     int32(15),
+					"created_at": createdAt,
+				}, nil
+			}
+			return nil, nil
+		}))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []map[string]any{
+		{
+			"tag_name": "All",
+		},
+	}, tables[tableTags.TableID()])
+
+	assert.DeepEqual(t, []map[string]any{
+		{
+			"postid": 1,
+			"title":  "First post",
+			"tag_id": int32(15),
+		},
+	}, tables[tablePosts.TableID()])
+
+	assert.Equal(t, int64(15), tagAll.TagID)
+	assert.Equal(t, createdAt, tagAll.CreatedAt)
+	assert.Assert(t, post1.TagID != nil)
+	assert.Equal(t, int64(15), *post1.TagID)
+}
+
+func TestScan(t *testing.T) {
+	var tv tag
+	err := Scan(debefix.MapValues{
+		"tag_id":   int64(10),
+		"tag_name": []byte("Scanned"),
+		"color":    "red",
+	}, &tv)
+	assert.NilError(t, err)
+
+	assert.Equal(t, int64(10), tv.TagID)
+	assert.Equal(t, "Scanned", tv.Name)
+	assert.DeepEqual(t, sql.NullString{String: "red", Valid: true}, tv.Color)
+
+	err = Scan(debefix.MapValues{
+		"tag_id": "invalid",
+	}, &tv)
+	assert.ErrorContains(t, err, "cannot assign")
+
+	for _, value := range []any{1.5, uint64(1 << 63), 1e30} {
+		err = Scan(debefix.MapValues{
+			"tag_id": value,
+		}, &tv)
+		assert.ErrorContains(t, err, "cannot be represented as int64")
+	}
+	err = Scan(debefix.MapValues{
+		"tag_id": 12.0,
+	}, &tv)
+	assert.NilError(t, err)
+	assert.Equal(t, int64(12), tv.TagID)
+}
+
+type Audit struct {
+	CreatedBy string `db:"created_by"`
+	Version   int    `db:"version,generated"`
+}
+
+type auditedTag struct {
+	TagID int64 `db:"tag_id"`
+	*Audit
+}
+
+func TestEmbeddedPointer(t *testing.T) {
+	values, err := Values(auditedTag{TagID: 1})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, debefix.MapValues{
+		"tag_id":     int64(1),
+		"created_by": "",
+		"version":    debefix.ResolveValueResolve(),
+	}, values)
+
+	var tv auditedTag
+	err = Scan(debefix.MapValues{
+		"tag_id":     int64(1),
+		"created_by": "admin",
+		"version":    int64(3),
+	}, &tv)
+	assert.NilError(t, err)
+	assert.Equal(t, int64(1), tv.TagID)
+	assert.DeepEqual(t, &Audit{CreatedBy: "admin", Version: 3}, tv.Audit)
+}
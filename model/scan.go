@@ -0,0 +1,105 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+// assignValue sets a struct field from a value, doing the conversions usually needed for values returned by
+// database drivers.
+func assignValue(dest reflect.Value, value any) error {
+	if value == nil {
+		dest.SetZero()
+		return nil
+	}
+
+	if dest.CanAddr() && dest.Addr().Type().Implements(scannerType) {
+		return dest.Addr().Interface().(sql.Scanner).Scan(value)
+	}
+
+	sv := reflect.ValueOf(value)
+	for sv.Kind() == reflect.Pointer {
+		if sv.IsNil() {
+			dest.SetZero()
+			return nil
+		}
+		if sv.Type().AssignableTo(dest.Type()) {
+			dest.Set(sv)
+			return nil
+		}
+		sv = sv.Elem()
+	}
+
+	if sv.Type().AssignableTo(dest.Type()) {
+		dest.Set(sv)
+		return nil
+	}
+
+	if dest.Kind() == reflect.Pointer {
+		nv := reflect.New(dest.Type().Elem())
+		if err := assignValue(nv.Elem(), sv.Interface()); err != nil {
+			return err
+		}
+		dest.Set(nv)
+		return nil
+	}
+
+	switch {
+	case isNumberKind(sv.Kind()) && isNumberKind(dest.Kind()):
+		cv := sv.Convert(dest.Type())
+		if isIntegerKind(dest.Kind()) && !integerConvertible(sv, cv) {
+			return fmt.Errorf("value %v of type %T cannot be represented as %s", value, value, dest.Type())
+		}
+		dest.Set(cv)
+		return nil
+	case isStringKind(sv.Type()) && isStringKind(dest.Type()):
+		dest.Set(sv.Convert(dest.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("cannot assign value of type %T to %s", value, dest.Type())
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	return isNumberKind(kind) && kind != reflect.Float32 && kind != reflect.Float64
+}
+
+// integerConvertible returns whether the number sv was converted to the integer cv without truncation or overflow.
+func integerConvertible(sv, cv reflect.Value) bool {
+	switch sv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if sv.Int() < 0 && !cv.CanInt() {
+			return false
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if cv.CanInt() && cv.Int() < 0 {
+			return false
+		}
+	case reflect.Float32, reflect.Float64:
+		f := sv.Float()
+		if f != math.Trunc(f) || (f < 0 && !cv.CanInt()) {
+			return false
+		}
+	}
+	return cv.Convert(sv.Type()).Equal(sv)
+}
+
+// isStringKind returns whether the type is a string or a []byte.
+func isStringKind(t reflect.Type) bool {
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+}
@@ -0,0 +1,24 @@
+module github.com/rrgmc/debefix-db/otel/v2
+
+go 1.23
+
+require (
+	github.com/rrgmc/debefix-db/v2 v2.1.0
+	github.com/rrgmc/debefix/v2 v2.0.6
+	go.opentelemetry.io/otel v1.34.0
+	go.opentelemetry.io/otel/sdk v1.34.0
+	go.opentelemetry.io/otel/trace v1.34.0
+	gotest.tools/v3 v3.5.1
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+)
+
+replace github.com/rrgmc/debefix-db/v2 => ..
@@ -0,0 +1,241 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	gootel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/rrgmc/debefix-db/otel/v2"
+
+// Tracer creates OpenTelemetry spans for debefix resolves. Resolve creates the parent span, ResolveDBMiddleware
+// creates a child span per table, and QueryInterfaceMiddleware a child span per executed statement.
+type Tracer struct {
+	tracer   trace.Tracer
+	dbSystem string
+}
+
+// New creates a Tracer.
+func New(options ...Option) *Tracer {
+	var optns tracerOptions
+	for _, opt := range options {
+		opt(&optns)
+	}
+	if optns.tracerProvider == nil {
+		optns.tracerProvider = gootel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer:   optns.tracerProvider.Tracer(instrumentationName),
+		dbSystem: optns.dbSystem,
+	}
+}
+
+// Option is an option for New.
+type Option func(*tracerOptions)
+
+// WithTracerProvider sets the tracer provider. The default is the global one.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(o *tracerOptions) {
+		o.tracerProvider = tracerProvider
+	}
+}
+
+// WithDBSystem sets the "db.system" attribute of the spans, like "postgresql" or "mysql".
+func WithDBSystem(dbSystem string) Option {
+	return func(o *tracerOptions) {
+		o.dbSystem = dbSystem
+	}
+}
+
+type tracerOptions struct {
+	tracerProvider trace.TracerProvider
+	dbSystem       string
+}
+
+// Resolve calls [debefix.Resolve] inside a "debefix.Resolve" span. The table spans created by ResolveDBMiddleware
+// are only created when the resolve is called using this function or ResolveParallel.
+func (t *Tracer) Resolve(ctx context.Context, data *debefix.Data, resolveFunc debefix.ResolveCallback,
+	options ...debefix.ResolveOption) (*debefix.ResolvedData, error) {
+	return t.resolve(ctx, func(ctx context.Context) (*debefix.ResolvedData, error) {
+		return debefix.Resolve(ctx, data, resolveFunc, options...)
+	})
+}
+
+// ResolveParallel calls [db.ResolveParallel] inside a "debefix.Resolve" span.
+func (t *Tracer) ResolveParallel(ctx context.Context, data *debefix.Data, provider db.ResolveDBCallbackProvider,
+	options ...db.ParallelOption) (*debefix.ResolvedData, error) {
+	return t.resolve(ctx, func(ctx context.Context) (*debefix.ResolvedData, error) {
+		return db.ResolveParallel(ctx, data, provider, options...)
+	})
+}
+
+func (t *Tracer) resolve(ctx context.Context,
+	resolveFn func(ctx context.Context) (*debefix.ResolvedData, error)) (*debefix.ResolvedData, error) {
+	ctx, span := t.tracer.Start(ctx, "debefix.Resolve", trace.WithAttributes(t.systemAttrs()...))
+	defer span.End()
+
+	run := &resolveRun{
+		span:   span,
+		tables: map[string]*tableSpan{},
+	}
+	resolved, err := resolveFn(context.WithValue(ctx, resolveRunContextKey{}, run))
+	run.end()
+
+	span.SetAttributes(
+		attribute.Int("debefix.table_count", len(run.tableOrder)),
+		attribute.Int("debefix.row_count", run.rowCount),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return resolved, err
+}
+
+// ResolveDBMiddleware returns a db.ResolveDBMiddleware which creates a span for each resolved table, as a child of
+// the span created by Resolve. All rows of the same table are part of the same span, which lasts from the start of
+// the first row to the end of the last one.
+func (t *Tracer) ResolveDBMiddleware() db.ResolveDBMiddleware {
+	return func(next db.ResolveDBCallback) db.ResolveDBCallback {
+		return func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+			returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			run, ok := ctx.Value(resolveRunContextKey{}).(*resolveRun)
+			if !ok {
+				return next(ctx, resolveInfo, fields, returnFields)
+			}
+
+			ctx = trace.ContextWithSpan(ctx, run.startTable(ctx, t, resolveInfo.TableID))
+			ret, err := next(ctx, resolveInfo, fields, returnFields)
+			run.rowDone(resolveInfo.TableID, err)
+			return ret, err
+		}
+	}
+}
+
+// QueryInterfaceMiddleware returns a sql.QueryInterfaceMiddleware which creates a span for each executed statement.
+// The span context is passed to the next QueryInterface, so it is propagated to the database calls.
+func (t *Tracer) QueryInterfaceMiddleware() sql.QueryInterfaceMiddleware {
+	return func(next sql.QueryInterface) sql.QueryInterface {
+		return sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+			returnFieldNames []string, args ...any) (map[string]any, error) {
+			operation := "QUERY"
+			if queryInfo, ok := sql.QueryInfoFromContext(ctx); ok {
+				operation = resolveTypeOperation(queryInfo.ResolveDBInfo.Type)
+			}
+
+			attrs := append(t.systemAttrs(),
+				attribute.String("db.statement", query),
+				attribute.String("db.operation", operation),
+				attribute.String("db.sql.table", tableID.TableID()),
+				attribute.Int("debefix.arg_count", len(args)),
+				attribute.StringSlice("debefix.return_field_names", returnFieldNames),
+			)
+
+			ctx, span := t.tracer.Start(ctx, operation+" "+tableID.TableID(),
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			ret, err := next.Query(ctx, tableID, query, returnFieldNames, args...)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return ret, err
+			}
+
+			span.SetAttributes(attribute.Int("debefix.returned_field_count", len(ret)))
+			return ret, nil
+		})
+	}
+}
+
+func (t *Tracer) systemAttrs() []attribute.KeyValue {
+	if t.dbSystem == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("db.system", t.dbSystem)}
+}
+
+type resolveRunContextKey struct{}
+
+// resolveRun keeps the table span state of a resolve call. Tables may be resolved concurrently by
+// db.ResolveParallel, so the state is kept per table.
+type resolveRun struct {
+	span trace.Span // the resolve span.
+
+	mu         sync.Mutex
+	tables     map[string]*tableSpan
+	tableOrder []string
+	rowCount   int
+}
+
+type tableSpan struct {
+	span    trace.Span
+	rows    int
+	err     error
+	lastEnd time.Time
+}
+
+// startTable returns the span of the table, starting it if it is the first row of the table.
+func (r *resolveRun) startTable(ctx context.Context, t *Tracer, tableID debefix.TableID) trace.Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ts, ok := r.tables[tableID.TableID()]; ok {
+		return ts.span
+	}
+
+	_, span := t.tracer.Start(trace.ContextWithSpan(ctx, r.span), "debefix.Table "+tableID.TableID(),
+		trace.WithAttributes(append(t.systemAttrs(), attribute.String("db.sql.table", tableID.TableID()))...))
+	r.tables[tableID.TableID()] = &tableSpan{span: span}
+	r.tableOrder = append(r.tableOrder, tableID.TableID())
+	return span
+}
+
+func (r *resolveRun) rowDone(tableID debefix.TableID, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := r.tables[tableID.TableID()]
+	ts.lastEnd = time.Now()
+	if err != nil {
+		ts.err = err
+		return
+	}
+	ts.rows++
+	r.rowCount++
+}
+
+// end ends the table spans, in the order they were started.
+func (r *resolveRun) end() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tableID := range r.tableOrder {
+		ts := r.tables[tableID]
+		ts.span.SetAttributes(attribute.Int("debefix.row_count", ts.rows))
+		if ts.err != nil {
+			ts.span.RecordError(ts.err)
+			ts.span.SetStatus(codes.Error, ts.err.Error())
+		}
+		ts.span.End(trace.WithTimestamp(ts.lastEnd))
+	}
+}
+
+func resolveTypeOperation(resolveType debefix.ResolveType) string {
+	switch resolveType {
+	case debefix.ResolveTypeAdd:
+		return "INSERT"
+	case debefix.ResolveTypeUpdate:
+		return "UPDATE"
+	default:
+		return "QUERY"
+	}
+}
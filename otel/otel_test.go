@@ -0,0 +1,158 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags  = debefix.TableName("public.tags")
+	tablePosts = debefix.TableName("public.posts")
+)
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := New(WithTracerProvider(tp), WithDBSystem("postgresql"))
+
+	qi := tracer.QueryInterfaceMiddleware()(sql.QueryInterfaceFunc(func(ctx context.Context,
+		tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+		if tableID.TableID() == tablePosts.TableID() {
+			return nil, errors.New("insert failed")
+		}
+		return map[string]any{"tag_id": 10}, nil
+	}))
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("go"),
+			"name":   "Go",
+		},
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"name":   "JavaScript",
+		},
+	)
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "go", "tag_id"),
+		},
+	)
+
+	_, err := tracer.Resolve(ctx, data, db.ResolveFunc(tracer.ResolveDBMiddleware()(
+		sql.ResolveDBFunc(qi, sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{})))))
+	assert.ErrorContains(t, err, "insert failed")
+
+	spans := exporter.GetSpans()
+
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	assert.DeepEqual(t, []string{
+		"INSERT public.tags",
+		"INSERT public.tags",
+		"INSERT public.posts",
+		"debefix.Table public.tags",
+		"debefix.Table public.posts",
+		"debefix.Resolve",
+	}, names)
+
+	resolveSpan := spans[5]
+	assert.Assert(t, !resolveSpan.Parent.IsValid())
+	assert.Equal(t, codes.Error, resolveSpan.Status.Code)
+	assert.Equal(t, int64(2), spanAttr(resolveSpan, "debefix.table_count").AsInt64())
+	assert.Equal(t, int64(2), spanAttr(resolveSpan, "debefix.row_count").AsInt64())
+
+	tagsSpan := spans[3]
+	assert.Equal(t, resolveSpan.SpanContext.SpanID(), tagsSpan.Parent.SpanID())
+	assert.Equal(t, codes.Unset, tagsSpan.Status.Code)
+	assert.Equal(t, int64(2), spanAttr(tagsSpan, "debefix.row_count").AsInt64())
+
+	tagStatementSpan := spans[0]
+	assert.Equal(t, tagsSpan.SpanContext.SpanID(), tagStatementSpan.Parent.SpanID())
+	assert.Equal(t, "postgresql", spanAttr(tagStatementSpan, "db.system").AsString())
+	assert.Equal(t, "INSERT INTO public.tags (name) VALUES (?) RETURNING tag_id",
+		spanAttr(tagStatementSpan, "db.statement").AsString())
+	assert.Equal(t, int64(1), spanAttr(tagStatementSpan, "debefix.returned_field_count").AsInt64())
+
+	postsSpan := spans[4]
+	assert.Equal(t, codes.Error, postsSpan.Status.Code)
+	assert.Equal(t, int64(0), spanAttr(postsSpan, "debefix.row_count").AsInt64())
+	assert.Equal(t, codes.Error, spans[2].Status.Code)
+	assert.Equal(t, postsSpan.SpanContext.SpanID(), spans[2].Parent.SpanID())
+}
+
+func TestTracerResolveParallel(t *testing.T) {
+	ctx := context.WithValue(context.Background(), testContextKey{}, "test")
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := New(WithTracerProvider(tp))
+
+	tableUsers := debefix.TableName("public.users")
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{"tag_id": 1},
+		debefix.MapValues{"tag_id": 2},
+	)
+	data.AddValues(tableUsers,
+		debefix.MapValues{"user_id": 1},
+	)
+
+	_, err := tracer.ResolveParallel(ctx, data, func(ctx context.Context) (db.ResolveDBCallback, func() error, error) {
+		return tracer.ResolveDBMiddleware()(func(ctx context.Context, resolveInfo db.ResolveDBInfo,
+			fields map[string]any, returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			if ctx.Value(testContextKey{}) != "test" {
+				return nil, errors.New("context values not propagated")
+			}
+			return nil, nil
+		}), func() error { return nil }, nil
+	})
+	assert.NilError(t, err)
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 3, len(spans))
+
+	resolveSpan := spans[2]
+	assert.Equal(t, "debefix.Resolve", resolveSpan.Name)
+	assert.Equal(t, int64(2), spanAttr(resolveSpan, "debefix.table_count").AsInt64())
+	assert.Equal(t, int64(3), spanAttr(resolveSpan, "debefix.row_count").AsInt64())
+
+	rowCounts := map[string]int64{}
+	for _, span := range spans[:2] {
+		assert.Equal(t, resolveSpan.SpanContext.SpanID(), span.Parent.SpanID())
+		rowCounts[span.Name] = spanAttr(span, "debefix.row_count").AsInt64()
+	}
+	assert.DeepEqual(t, map[string]int64{
+		"debefix.Table public.tags":  2,
+		"debefix.Table public.users": 1,
+	}, rowCounts)
+}
+
+type testContextKey struct{}
+
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,381 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// ResolveDBCallbackProvider returns a ResolveDBCallback which uses its own database connection, and a function to
+// release the connection after the resolve.
+type ResolveDBCallbackProvider func(ctx context.Context) (callback ResolveDBCallback, release func() error, err error)
+
+// ResolveParallel resolves groups of tables without dependencies between them concurrently, each one using a
+// callback returned by provider. The rows of each group are resolved in the same order as [debefix.Resolve].
+// The first error cancels the context of the other groups.
+// Updates are resolved in the group of the table they target, see TableGroups.
+// Each group is resolved by a separate [debefix.Resolve] call, so the resolve options are applied to each group.
+// All groups share the same base time, which is the base time of the returned data.
+func ResolveParallel(ctx context.Context, data *debefix.Data, provider ResolveDBCallbackProvider,
+	options ...ParallelOption) (*debefix.ResolvedData, error) {
+	optns := parallelOptions{
+		workers: 4,
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	if data.Err() != nil {
+		return nil, data.Err()
+	}
+
+	groups := TableGroups(data)
+	baseTime := time.Now()
+
+	// updates which don't target a table of the data are resolved in the first group.
+	groupUpdates := make([][]debefix.Update, len(groups))
+	for _, update := range data.Updates {
+		if len(groups) == 0 {
+			break
+		}
+		groupIdx := 0
+		if updateTables, ok := updateTableIDs(update); ok {
+			if idx := slices.IndexFunc(groups, func(group []string) bool {
+				return slices.Contains(group, updateTables[0])
+			}); idx >= 0 {
+				groupIdx = idx
+			}
+		}
+		groupUpdates[groupIdx] = append(groupUpdates[groupIdx], update)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]*debefix.ResolvedData, len(groups))
+	var firstErr error
+	var errOnce sync.Once
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel(err)
+		})
+	}
+
+	sem := make(chan struct{}, max(optns.workers, 1))
+	var wg sync.WaitGroup
+	for groupIdx, group := range groups {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resolved, err := resolveGroup(ctx, data, group, groupUpdates[groupIdx], baseTime, provider,
+				optns.resolveOptions...)
+			if err != nil {
+				setErr(err)
+				return
+			}
+			results[groupIdx] = resolved
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	ret := debefix.NewResolvedData()
+	ret.BaseTime = baseTime
+	for _, resolved := range results {
+		for tableID, table := range resolved.Tables {
+			ret.Tables[tableID] = table
+		}
+		ret.Updates = append(ret.Updates, resolved.Updates...)
+		ret.TableOrder = append(ret.TableOrder, resolved.TableOrder...)
+	}
+	return ret, nil
+}
+
+// ParallelOption is an option for ResolveParallel.
+type ParallelOption func(*parallelOptions)
+
+// WithParallelWorkers sets the maximum number of groups resolved concurrently. The default is 4.
+func WithParallelWorkers(workers int) ParallelOption {
+	return func(o *parallelOptions) {
+		o.workers = workers
+	}
+}
+
+// WithParallelResolveOptions sets options for the [debefix.Resolve] call of each group.
+func WithParallelResolveOptions(options ...debefix.ResolveOption) ParallelOption {
+	return func(o *parallelOptions) {
+		o.resolveOptions = append(o.resolveOptions, options...)
+	}
+}
+
+type parallelOptions struct {
+	workers        int
+	resolveOptions []debefix.ResolveOption
+}
+
+// TableGroups returns groups of tables which don't have dependencies with tables of other groups. The table IDs in
+// each group, and the groups by their first table ID, are sorted.
+// Updates, including the ones added to rows by [debefix.Data.UpdateAfter], join the groups of the table they
+// target, of the tables referenced by their values, and of the row they are added to. If the target table of an
+// update can't be determined, all tables are returned in a single group.
+func TableGroups(data *debefix.Data) [][]string {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(tableID string) string {
+		if p, ok := parent[tableID]; ok && p != tableID {
+			parent[tableID] = find(p)
+			return parent[tableID]
+		}
+		parent[tableID] = tableID
+		return tableID
+	}
+	union := func(tableIDs ...string) {
+		var root string
+		for _, tableID := range tableIDs {
+			if _, ok := data.Tables[tableID]; !ok {
+				continue
+			}
+			if root == "" {
+				root = find(tableID)
+				continue
+			}
+			parent[find(tableID)] = root
+		}
+	}
+
+	allTables := slices.Collect(maps.Keys(data.Tables))
+	for tableID, table := range data.Tables {
+		find(tableID)
+		for _, dep := range table.Depends {
+			union(tableID, dep.TableID())
+		}
+		for _, row := range table.Rows {
+			for _, update := range row.Updates {
+				updateTables, ok := updateTableIDs(update)
+				if !ok {
+					updateTables = allTables
+				}
+				union(append([]string{tableID}, updateTables...)...)
+			}
+		}
+	}
+	for _, update := range data.Updates {
+		updateTables, ok := updateTableIDs(update)
+		if !ok {
+			updateTables = allTables
+		}
+		union(updateTables...)
+	}
+
+	groupMap := map[string][]string{}
+	for tableID := range data.Tables {
+		root := find(tableID)
+		groupMap[root] = append(groupMap[root], tableID)
+	}
+
+	var ret [][]string
+	for _, group := range groupMap {
+		slices.Sort(group)
+		ret = append(ret, group)
+	}
+	slices.SortFunc(ret, func(a, b []string) int {
+		return cmp.Compare(a[0], b[0])
+	})
+	return ret
+}
+
+// updateTableIDs returns the table the update targets, followed by the tables referenced by its values. It returns
+// false if the target table can't be determined.
+func updateTableIDs(update debefix.Update) ([]string, bool) {
+	var target any
+	switch uq := update.Query.(type) {
+	case debefix.UpdateQueryQueryRow:
+		target = uq.QueryRow
+	case *debefix.UpdateQueryQueryRow:
+		target = uq.QueryRow
+	case debefix.UpdateQueryQueryRows:
+		target = uq.QueryRows
+	case *debefix.UpdateQueryQueryRows:
+		target = uq.QueryRows
+	default:
+		target = update.Query
+	}
+
+	var ret []string
+	switch tt := target.(type) {
+	case debefix.InternalIDRef:
+		ret = append(ret, tt.TableID.TableID())
+	case *debefix.InternalIDRef:
+		ret = append(ret, tt.TableID.TableID())
+	case debefix.ValueDependencies:
+		for _, tableID := range tt.TableDependencies() {
+			ret = append(ret, tableID.TableID())
+		}
+	}
+	if len(ret) == 0 {
+		return nil, false
+	}
+
+	var values debefix.Values
+	switch ua := update.Action.(type) {
+	case debefix.UpdateActionSetValues:
+		values = ua.Values
+	case *debefix.UpdateActionSetValues:
+		values = ua.Values
+	}
+	if values != nil {
+		for _, value := range values.All {
+			if vd, ok := value.(debefix.ValueDependencies); ok {
+				for _, tableID := range vd.TableDependencies() {
+					ret = append(ret, tableID.TableID())
+				}
+			}
+		}
+	}
+	return ret, true
+}
+
+func resolveGroup(ctx context.Context, data *debefix.Data, group []string, updates []debefix.Update,
+	baseTime time.Time, provider ResolveDBCallbackProvider,
+	options ...debefix.ResolveOption) (_ *debefix.ResolvedData, err error) {
+	callback, release, err := provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rerr := release(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	groupData := debefix.NewData()
+	for _, tableID := range group {
+		groupData.Tables[tableID] = baseTimeTable(data.Tables[tableID], baseTime)
+	}
+	for _, update := range updates {
+		groupData.Updates = append(groupData.Updates, baseTimeUpdate(update, baseTime))
+	}
+
+	return debefix.Resolve(ctx, groupData, ResolveFunc(callback), options...)
+}
+
+// baseTimeTable returns a copy of the table whose values are resolved using baseTime as the base time, as
+// [debefix.Resolve] don't allow setting it.
+func baseTimeTable(table *debefix.Table, baseTime time.Time) *debefix.Table {
+	ret := &debefix.Table{
+		TableID: table.TableID,
+		Depends: table.Depends,
+		Rows:    make([]*debefix.Row, 0, len(table.Rows)),
+	}
+	for _, row := range table.Rows {
+		values := debefix.MapValues{}
+		for fieldName, fieldValue := range row.Values.All {
+			if wrapped, ok := baseTimeFieldValue(fieldValue, baseTime); ok {
+				fieldValue = wrapped
+			}
+			values[fieldName] = fieldValue
+		}
+		rowUpdates := make([]debefix.Update, 0, len(row.Updates))
+		for _, update := range row.Updates {
+			rowUpdates = append(rowUpdates, baseTimeUpdate(update, baseTime))
+		}
+		ret.Rows = append(ret.Rows, &debefix.Row{
+			InternalID:        row.InternalID,
+			RefID:             row.RefID,
+			Values:            values,
+			Updates:           rowUpdates,
+			ResolvedCallbacks: row.ResolvedCallbacks,
+		})
+	}
+	return ret
+}
+
+func baseTimeUpdate(update debefix.Update, baseTime time.Time) debefix.Update {
+	return debefix.Update{
+		Query:  update.Query,
+		Action: baseTimeUpdateAction{action: update.Action, baseTime: baseTime},
+	}
+}
+
+// baseTimeFieldValue wraps Value and ValueMultiple values to be resolved using baseTime as the base time. It
+// returns false if the value don't need to be wrapped.
+func baseTimeFieldValue(fieldValue any, baseTime time.Time) (any, bool) {
+	switch fv := fieldValue.(type) {
+	case baseTimeValue, baseTimeValueMultiple:
+		return nil, false
+	case debefix.Value:
+		return baseTimeValue{value: fv, baseTime: baseTime}, true
+	case debefix.ValueMultiple:
+		return baseTimeValueMultiple{value: fv, baseTime: baseTime}, true
+	default:
+		return nil, false
+	}
+}
+
+// withBaseTime returns a shallow copy of resolvedData with a different base time.
+func withBaseTime(resolvedData *debefix.ResolvedData, baseTime time.Time) *debefix.ResolvedData {
+	ret := *resolvedData
+	ret.BaseTime = baseTime
+	return &ret
+}
+
+type baseTimeValue struct {
+	value    debefix.Value
+	baseTime time.Time
+}
+
+func (v baseTimeValue) ResolveValue(ctx context.Context, resolvedData *debefix.ResolvedData,
+	values debefix.Values) (any, bool, error) {
+	return v.value.ResolveValue(ctx, withBaseTime(resolvedData, v.baseTime), values)
+}
+
+type baseTimeValueMultiple struct {
+	value    debefix.ValueMultiple
+	baseTime time.Time
+}
+
+func (v baseTimeValueMultiple) Resolve(ctx context.Context, resolvedData *debefix.ResolvedData,
+	tableID debefix.TableID, fieldName string, values debefix.ValuesMutable) error {
+	return v.value.Resolve(ctx, withBaseTime(resolvedData, v.baseTime), tableID, fieldName, values)
+}
+
+// baseTimeUpdateAction wraps the values set by an update action to be resolved using baseTime as the base time.
+type baseTimeUpdateAction struct {
+	action   debefix.UpdateAction
+	baseTime time.Time
+}
+
+func (a baseTimeUpdateAction) UpdateRow(ctx context.Context, resolvedData *debefix.ResolvedData,
+	tableID debefix.TableID, row *debefix.Row) error {
+	if err := a.action.UpdateRow(ctx, resolvedData, tableID, row); err != nil {
+		return err
+	}
+	for fieldName, fieldValue := range row.Values.All {
+		if wrapped, ok := baseTimeFieldValue(fieldValue, a.baseTime); ok {
+			row.Values.Set(fieldName, wrapped)
+		}
+	}
+	return nil
+}
@@ -2,8 +2,15 @@ package db
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/rrgmc/debefix/v2"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
@@ -138,3 +145,265 @@ func TestResolveGenerated(t *testing.T) {
 
 	assert.DeepEqual(t, []string{"public.tags"}, tableOrder)
 }
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+
+	middleware := func(name string) ResolveDBMiddleware {
+		return func(next ResolveDBCallback) ResolveDBCallback {
+			return func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+				returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+				calls = append(calls, name)
+				return next(ctx, resolveInfo, fields, returnFields)
+			}
+		}
+	}
+
+	callback := Chain(middleware("first"), middleware("second"))(
+		func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+			returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			calls = append(calls, "callback")
+			return nil, nil
+		})
+
+	data := debefix.NewData()
+	data.Add(tableTags, debefix.MapValues{"tag_id": 2})
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(callback))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"first", "second", "callback"}, calls)
+}
+
+func parallelTestData() *debefix.Data {
+	tableUsers := debefix.TableName("public.users")
+	tableComments := debefix.TableName("public.comments")
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{"tag_id": 1, "_refid": debefix.SetValueRefID("go")},
+		debefix.MapValues{"tag_id": 2, "_refid": debefix.SetValueRefID("js")},
+	)
+	data.AddValues(tablePosts,
+		debefix.MapValues{"post_id": 1, "tag_id": debefix.ValueRefID(tableTags, "go", "tag_id")},
+	)
+	data.AddValues(tableUsers,
+		debefix.MapValues{"user_id": 1, "_refid": debefix.SetValueRefID("john")},
+		debefix.MapValues{"user_id": 2},
+	)
+	data.AddValues(tableComments,
+		debefix.MapValues{"comment_id": 1, "user_id": debefix.ValueRefID(tableUsers, "john", "user_id")},
+	)
+	return data
+}
+
+func TestTableGroups(t *testing.T) {
+	assert.DeepEqual(t, [][]string{
+		{"public.comments", "public.users"},
+		{"public.posts", "public.tags"},
+	}, TableGroups(parallelTestData()))
+}
+
+func TestResolveParallel(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var connections [][]string
+	released := 0
+
+	provider := func(ctx context.Context) (ResolveDBCallback, func() error, error) {
+		mu.Lock()
+		connIdx := len(connections)
+		connections = append(connections, nil)
+		mu.Unlock()
+
+		return func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+				returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				connections[connIdx] = append(connections[connIdx], resolveInfo.TableID.TableID())
+				return nil, nil
+			}, func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				released++
+				return nil
+			}, nil
+	}
+
+	resolved, err := ResolveParallel(ctx, parallelTestData(), provider, WithParallelWorkers(2))
+	assert.NilError(t, err)
+
+	assert.Equal(t, 2, released)
+	assert.Assert(t, is.Len(connections, 2))
+	slices.SortFunc(connections, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	assert.DeepEqual(t, [][]string{
+		{"public.tags", "public.tags", "public.posts"},
+		{"public.users", "public.users", "public.comments"},
+	}, connections)
+
+	assert.DeepEqual(t, []string{"public.comments", "public.posts", "public.tags", "public.users"},
+		slices.Sorted(maps.Keys(resolved.Tables)))
+	assert.Equal(t, 4, len(resolved.TableOrder))
+
+	postTagID, err := resolved.FindRefIDRowValue(debefix.ValueRefID(tableTags, "js", "tag_id"))
+	assert.NilError(t, err)
+	assert.Equal(t, 2, postTagID)
+
+	// the first error cancels the other groups.
+	_, err = ResolveParallel(ctx, parallelTestData(), func(ctx context.Context) (ResolveDBCallback, func() error,
+		error) {
+		return func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+			returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			if resolveInfo.TableID.TableID() == tableTags.TableID() {
+				return nil, errors.New("tags failed")
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, func() error { return nil }, nil
+	})
+	assert.ErrorContains(t, err, "tags failed")
+}
+
+func TestResolveParallelUpdates(t *testing.T) {
+	ctx := context.Background()
+
+	tableUsers := debefix.TableName("public.users")
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{"tag_id": 1, "created_at": debefix.ValueBaseTimeAdd()},
+	)
+	data.AddValues(tableUsers,
+		debefix.MapValues{"user_id": 1, "_refid": debefix.SetValueRefID("john")},
+	)
+	data.Update(debefix.ValueRefID(tableUsers, "john", "user_id").UpdateQuery([]string{"user_id"}),
+		debefix.UpdateActionSetValues{Values: debefix.MapValues{
+			"updated_at": debefix.ValueBaseTimeAdd(debefix.WithAddHours(1)),
+		}})
+
+	assert.DeepEqual(t, [][]string{{"public.tags"}, {"public.users"}}, TableGroups(data))
+
+	var mu sync.Mutex
+	calls := map[string][]ResolveDBInfo{}
+	values := map[string]any{}
+
+	provider := func(ctx context.Context) (ResolveDBCallback, func() error, error) {
+		return func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+			returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[resolveInfo.TableID.TableID()] = append(calls[resolveInfo.TableID.TableID()], resolveInfo)
+			for _, fn := range []string{"created_at", "updated_at"} {
+				if fv, ok := fields[fn]; ok {
+					values[fn] = fv
+				}
+			}
+			return nil, nil
+		}, func() error { return nil }, nil
+	}
+
+	resolved, err := ResolveParallel(ctx, data, provider)
+	assert.NilError(t, err)
+
+	assert.Equal(t, 1, len(calls["public.tags"]))
+	assert.Equal(t, 2, len(calls["public.users"]))
+	assert.Equal(t, debefix.ResolveTypeUpdate, calls["public.users"][1].Type)
+
+	assert.DeepEqual(t, map[string]any{
+		"created_at": resolved.BaseTime,
+		"updated_at": resolved.BaseTime.Add(time.Hour),
+	}, values)
+}
+
+func TestRouteResolveDB(t *testing.T) {
+	ctx := context.Background()
+
+	tableEvents := debefix.TableName("audit.events")
+	tableLogs := debefix.TableName("logs")
+
+	var calls []string
+	callback := func(name string) ResolveDBCallback {
+		return func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+			returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+			calls = append(calls, name+": "+resolveInfo.TableID.TableID())
+			ret := map[string]any{}
+			for fn := range returnFields {
+				ret[fn] = 15
+			}
+			return ret, nil
+		}
+	}
+
+	var eventTagID any
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("go"),
+		},
+	)
+	data.Add(tableEvents,
+		debefix.MapValues{
+			"event_id": 1,
+			"tag_id":   debefix.ValueRefID(tableTags, "go", "tag_id"),
+		},
+		debefix.WithDataAddResolvedCallback(func(ctx context.Context, resolvedData *debefix.ResolvedData,
+			resolveInfo debefix.ResolveInfo, resolvedRow *debefix.Row) error {
+			eventTagID, _ = resolvedRow.Values.Get("tag_id")
+			return nil
+		}),
+	)
+
+	cb := RouteResolveDB(
+		Route{Match: MatchSchemas("audit"), Callback: callback("audit")},
+		Route{Match: MatchTables("public.tags"), Callback: callback("main")},
+	)
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(cb))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"main: public.tags", "audit: audit.events"}, calls)
+	assert.Equal(t, 15, eventTagID)
+
+	data.AddValues(tableLogs, debefix.MapValues{"log_id": 1})
+	_, err = debefix.Resolve(ctx, data, ResolveFunc(cb))
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.ErrorContains(t, err, "no route for table 'logs'")
+}
+
+func TestDataHash(t *testing.T) {
+	newData := func(title string) *debefix.Data {
+		data := debefix.NewData()
+		tagIID := data.AddWithID(tableTags,
+			debefix.MapValues{
+				"tag_id":     debefix.ResolveValueResolve(),
+				"_refid":     debefix.SetValueRefID("go"),
+				"name":       "Go",
+				"created_at": debefix.ValueBaseTimeAdd(debefix.WithAddHours(1)),
+			})
+		data.AddValues(tablePosts,
+			debefix.MapValues{
+				"post_id": debefix.ValueUUID(uuid.MustParse("1b1fb7c1-4c2f-4bd2-9b6c-3c0e1f5e2a10")),
+				"title":   title,
+				"tag_id":  tagIID.ValueForField("tag_id"),
+			},
+		)
+		data.Update(tagIID.UpdateQuery([]string{"tag_id"}), debefix.UpdateActionSetValues{
+			Values: debefix.MapValues{"name": "Golang"},
+		})
+		return data
+	}
+
+	hash1, err := DataHash(newData("First post"))
+	assert.NilError(t, err)
+	hash2, err := DataHash(newData("First post"))
+	assert.NilError(t, err)
+	hash3, err := DataHash(newData("Second post"))
+	assert.NilError(t, err)
+
+	assert.Equal(t, hash1, hash2)
+	assert.Assert(t, hash1 != hash3)
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// ErrNoRoute is returned by routers when no route matches a table.
+var ErrNoRoute = errors.New("no route for table")
+
+// TableMatcher returns whether a route should handle a table.
+type TableMatcher func(tableID debefix.TableID) bool
+
+// MatchTables matches tables by their exact table ID.
+func MatchTables(tableIDs ...string) TableMatcher {
+	return func(tableID debefix.TableID) bool {
+		return slices.Contains(tableIDs, tableID.TableID())
+	}
+}
+
+// MatchSchemas matches tables whose table ID has one of the schema prefixes, like "audit" for "audit.events".
+func MatchSchemas(schemas ...string) TableMatcher {
+	return func(tableID debefix.TableID) bool {
+		return slices.ContainsFunc(schemas, func(schema string) bool {
+			return strings.HasPrefix(tableID.TableID(), schema+".")
+		})
+	}
+}
+
+// MatchAll matches all tables. It can be used as the last route, as a default.
+func MatchAll() TableMatcher {
+	return func(tableID debefix.TableID) bool {
+		return true
+	}
+}
+
+// Route is a route of RouteResolveDB.
+type Route struct {
+	Match    TableMatcher
+	Callback ResolveDBCallback
+}
+
+// RouteResolveDB returns a ResolveDBCallback which calls the callback of the first route matching the table, which
+// allows the data to be stored in multiple databases, each with its own dialect. Values referencing rows stored in
+// other databases, like [debefix.ValueRefID], work as usual, as they are resolved before the callback is called.
+// If no route matches the table, an error wrapping ErrNoRoute is returned.
+func RouteResolveDB(routes ...Route) ResolveDBCallback {
+	return func(ctx context.Context, resolveInfo ResolveDBInfo, fields map[string]any,
+		returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+		for _, route := range routes {
+			if route.Match(resolveInfo.TableID) {
+				return route.Callback(ctx, resolveInfo, fields, returnFields)
+			}
+		}
+		return nil, fmt.Errorf("%w '%s'", ErrNoRoute, resolveInfo.TableID.TableID())
+	}
+}
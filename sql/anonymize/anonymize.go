@@ -0,0 +1,207 @@
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/extract"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Rule returns the anonymized value of a column value. hash is a keyed hash of the original value, which is
+// the same for equal values in any table or column, so rules that derive the value from it are consistent across
+// foreign key references.
+// Rules are never called for nil values.
+type Rule func(value any, hash []byte) (any, error)
+
+// Anonymizer anonymizes column values using rules keyed by table and column.
+type Anonymizer struct {
+	salt        []byte
+	rules       map[string]map[string]Rule // table name: column name: rule
+	columnRules map[string]Rule            // column name: rule
+}
+
+// New creates an Anonymizer.
+func New(options ...Option) *Anonymizer {
+	ret := &Anonymizer{
+		rules:       map[string]map[string]Rule{},
+		columnRules: map[string]Rule{},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// Option is an option for New.
+type Option func(a *Anonymizer)
+
+// WithSalt sets the secret used to hash the values. Without a salt, values with low entropy like emails may be
+// recovered from the hashes.
+func WithSalt(salt string) Option {
+	return func(a *Anonymizer) {
+		a.salt = []byte(salt)
+	}
+}
+
+// WithRule sets the rule of a table column.
+func WithRule(tableName string, columnName string, rule Rule) Option {
+	return func(a *Anonymizer) {
+		if _, ok := a.rules[tableName]; !ok {
+			a.rules[tableName] = map[string]Rule{}
+		}
+		a.rules[tableName][columnName] = rule
+	}
+}
+
+// WithColumnRule sets the rule of a column in any table. Table rules set with WithRule have precedence.
+func WithColumnRule(columnName string, rule Rule) Option {
+	return func(a *Anonymizer) {
+		a.columnRules[columnName] = rule
+	}
+}
+
+// Value returns the anonymized value of a table column. If no rule is set for the column, the value is returned
+// unchanged.
+func (a *Anonymizer) Value(tableName string, columnName string, value any) (any, error) {
+	rule := a.rule(tableName, columnName)
+	if rule == nil || value == nil {
+		return value, nil
+	}
+	ret, err := rule(value, a.hash(value))
+	if err != nil {
+		return nil, fmt.Errorf("error anonymizing column '%s' of table '%s': %w", columnName, tableName, err)
+	}
+	return ret, nil
+}
+
+// Values anonymizes the values of a table row in place.
+func (a *Anonymizer) Values(tableName string, values map[string]any) error {
+	for _, columnName := range slices.Sorted(maps.Keys(values)) {
+		value, err := a.Value(tableName, columnName, values[columnName])
+		if err != nil {
+			return err
+		}
+		values[columnName] = value
+	}
+	return nil
+}
+
+func (a *Anonymizer) rule(tableName string, columnName string) Rule {
+	if tableRules, ok := a.rules[tableName]; ok {
+		if rule, ok := tableRules[columnName]; ok {
+			return rule
+		}
+	}
+	return a.columnRules[columnName]
+}
+
+// hash returns a keyed hash of the value. Values with the same string representation have the same hash, so a
+// numeric key and its string representation are anonymized to the same value.
+func (a *Anonymizer) hash(value any) []byte {
+	h := hmac.New(sha256.New, a.salt)
+	switch v := value.(type) {
+	case []byte:
+		h.Write(v)
+	default:
+		_, _ = fmt.Fprint(h, v)
+	}
+	return h.Sum(nil)
+}
+
+// NewQueryBuilder returns a sql.QueryBuilder which anonymizes the fields before building the query.
+func NewQueryBuilder(queryBuilder sql.QueryBuilder, anonymizer *Anonymizer) sql.QueryBuilder {
+	return &anonymizeQueryBuilder{queryBuilder: queryBuilder, anonymizer: anonymizer}
+}
+
+var _ sql.QueryBuilderArgFieldNames = (*anonymizeQueryBuilder)(nil)
+
+type anonymizeQueryBuilder struct {
+	queryBuilder sql.QueryBuilder
+	anonymizer   *Anonymizer
+}
+
+func (q *anonymizeQueryBuilder) BuildSQL(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+	returnFieldNames map[string]debefix.ResolveValue) (string, []any, error) {
+	fields, err := q.anonymizer.resolveFields(resolveInfo, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	return q.queryBuilder.BuildSQL(ctx, resolveInfo, fields, returnFieldNames)
+}
+
+// BuildSQLArgFieldNames implements sql.QueryBuilderArgFieldNames. The argument field names are only returned if the
+// wrapped QueryBuilder implements it.
+func (q *anonymizeQueryBuilder) BuildSQLArgFieldNames(ctx context.Context, resolveInfo db.ResolveDBInfo,
+	fields map[string]any, returnFieldNames map[string]debefix.ResolveValue) (string, []any, []string, error) {
+	fields, err := q.anonymizer.resolveFields(resolveInfo, fields)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if qb, ok := q.queryBuilder.(sql.QueryBuilderArgFieldNames); ok {
+		return qb.BuildSQLArgFieldNames(ctx, resolveInfo, fields, returnFieldNames)
+	}
+	query, args, err := q.queryBuilder.BuildSQL(ctx, resolveInfo, fields, returnFieldNames)
+	return query, args, nil, err
+}
+
+// QueryInterfaceMiddleware returns a sql.QueryInterfaceMiddleware which anonymizes the query arguments. The
+// argument field names are read from the sql.QueryInfo in the context, so the query must be generated by
+// sql.ResolveDBFunc with a QueryBuilder implementing sql.QueryBuilderArgFieldNames, otherwise an error is returned.
+// The returned values are not changed.
+func QueryInterfaceMiddleware(anonymizer *Anonymizer) sql.QueryInterfaceMiddleware {
+	return func(next sql.QueryInterface) sql.QueryInterface {
+		return sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+			returnFieldNames []string, args ...any) (map[string]any, error) {
+			queryInfo, ok := sql.QueryInfoFromContext(ctx)
+			if !ok || len(queryInfo.ArgFieldNames) != len(args) {
+				return nil, fmt.Errorf("argument field names of query of table '%s' are not available to anonymize it",
+					tableID.TableID())
+			}
+			anonymizedArgs := make([]any, len(args))
+			for i, arg := range args {
+				value, err := anonymizer.Value(tableID.TableName(), queryInfo.ArgFieldNames[i], arg)
+				if err != nil {
+					return nil, err
+				}
+				anonymizedArgs[i] = value
+			}
+			return next.Query(ctx, tableID, query, returnFieldNames, anonymizedArgs...)
+		})
+	}
+}
+
+// ResolveDBFunc returns a db.ResolveDBCallback which anonymizes the fields before calling the callback.
+// The returned values are not changed.
+func ResolveDBFunc(callback db.ResolveDBCallback, anonymizer *Anonymizer) db.ResolveDBCallback {
+	return func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+		returnFields map[string]debefix.ResolveValue) (returnValues map[string]any, err error) {
+		fields, err = anonymizer.resolveFields(resolveInfo, fields)
+		if err != nil {
+			return nil, err
+		}
+		return callback(ctx, resolveInfo, fields, returnFields)
+	}
+}
+
+// RowFilter returns an extract.RowFilter which anonymizes the extracted rows.
+func RowFilter(anonymizer *Anonymizer) extract.RowFilter {
+	return func(ctx context.Context, table string, row map[string]any) error {
+		return anonymizer.Values(table, row)
+	}
+}
+
+// resolveFields returns a copy of the fields with the anonymized values.
+func (a *Anonymizer) resolveFields(resolveInfo db.ResolveDBInfo, fields map[string]any) (map[string]any, error) {
+	ret := maps.Clone(fields)
+	if err := a.Values(resolveInfo.TableID.TableName(), ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
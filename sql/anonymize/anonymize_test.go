@@ -0,0 +1,187 @@
+package anonymize
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableUsers  = debefix.TableName("users")
+	tableOrders = debefix.TableName("orders")
+)
+
+func TestAnonymizer(t *testing.T) {
+	userPseudonym := Pseudonym("user")
+
+	a := New(
+		WithSalt("secret"),
+		WithRule("users", "name", FakeName()),
+		WithRule("users", "login", userPseudonym),
+		WithRule("orders", "login", userPseudonym),
+		WithRule("users", "phone", KeepFormat()),
+		WithRule("users", "notes", Null()),
+		WithRule("users", "document", Hash(8)),
+		WithColumnRule("email", FakeEmail("")),
+	)
+
+	user := map[string]any{
+		"user_id":  1,
+		"name":     "John Doe",
+		"login":    "jdoe",
+		"email":    "john@company.com",
+		"phone":    "+1 (555) 123-4567",
+		"notes":    "some notes",
+		"document": "123456",
+		"address":  nil,
+	}
+	assert.NilError(t, a.Values("users", user))
+
+	assert.Equal(t, 1, user["user_id"])
+	assert.Assert(t, user["name"] != "John Doe")
+	assert.Equal(t, "user_1", user["login"])
+	assert.Assert(t, regexp.MustCompile(`^[a-z]+\.[a-z]+\.\d+@example\.com$`).MatchString(user["email"].(string)),
+		"invalid email: %s", user["email"])
+	assert.Assert(t, regexp.MustCompile(`^\+\d \(\d{3}\) \d{3}-\d{4}$`).MatchString(user["phone"].(string)),
+		"invalid phone: %s", user["phone"])
+	assert.Assert(t, user["phone"] != "+1 (555) 123-4567")
+	assert.Equal(t, nil, user["notes"])
+	assert.Equal(t, 8, len(user["document"].(string)))
+	assert.Equal(t, nil, user["address"])
+
+	// the same values must be anonymized to the same value in other tables.
+	order := map[string]any{
+		"order_id": 10,
+		"login":    "jdoe",
+		"email":    "john@company.com",
+	}
+	assert.NilError(t, a.Values("orders", order))
+	assert.Equal(t, user["login"], order["login"])
+	assert.Equal(t, user["email"], order["email"])
+
+	other, err := a.Value("orders", "login", "mary")
+	assert.NilError(t, err)
+	assert.Equal(t, "user_2", other)
+
+	// a different salt must generate different values.
+	otherEmail, err := New(WithSalt("other"), WithColumnRule("email", FakeEmail(""))).
+		Value("users", "email", "john@company.com")
+	assert.NilError(t, err)
+	assert.Assert(t, otherEmail != user["email"])
+}
+
+func TestAnonymizerHashTypes(t *testing.T) {
+	a := New(WithColumnRule("id", Hash(0)))
+
+	intValue, err := a.Value("users", "id", int64(15))
+	assert.NilError(t, err)
+	stringValue, err := a.Value("users", "id", "15")
+	assert.NilError(t, err)
+
+	assert.Assert(t, intValue.(int64) >= 0)
+	assert.Equal(t, 64, len(stringValue.(string)))
+
+	_, err = a.Value("users", "id", 1.5)
+	assert.ErrorContains(t, err, "error anonymizing column 'id' of table 'users'")
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	a := New(WithColumnRule("email", FakeEmail("test.com")))
+
+	var queries []string
+	var queryArgs [][]any
+	qi := sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		queries = append(queries, query)
+		queryArgs = append(queryArgs, args)
+		return nil, nil
+	})
+
+	data := debefix.NewData()
+	data.AddValues(tableUsers,
+		debefix.MapValues{
+			"user_id": 1,
+			"email":   "john@company.com",
+		},
+	)
+
+	var orderFields map[string]any
+
+	data.AddValues(tableOrders,
+		debefix.MapValues{
+			"order_id": 10,
+			"email":    "john@company.com",
+		},
+	)
+
+	usersResolve := sql.ResolveFunc(qi, NewQueryBuilder(sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{}), a))
+	ordersResolve := db.ResolveFunc(ResolveDBFunc(func(ctx context.Context, resolveInfo db.ResolveDBInfo,
+		fields map[string]any, returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+		orderFields = fields
+		return nil, nil
+	}, a))
+
+	_, err := debefix.Resolve(ctx, data, func(ctx context.Context, resolveInfo debefix.ResolveInfo,
+		values debefix.ValuesMutable) error {
+		if resolveInfo.TableID.TableID() == tableUsers.TableID() {
+			return usersResolve(ctx, resolveInfo, values)
+		}
+		return ordersResolve(ctx, resolveInfo, values)
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{"INSERT INTO users (email, user_id) VALUES (?, ?)"}, queries)
+	assert.Assert(t, queryArgs[0][0] != "john@company.com")
+	assert.Equal(t, queryArgs[0][0], orderFields["email"])
+	assert.Equal(t, 10, orderFields["order_id"])
+}
+
+func TestQueryInterfaceMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	a := New(WithColumnRule("email", FakeEmail("test.com")))
+
+	var queryArgs []any
+	var argFieldNames []string
+	qi := sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		queryArgs = args
+		if queryInfo, ok := sql.QueryInfoFromContext(ctx); ok {
+			argFieldNames = queryInfo.ArgFieldNames
+		}
+		return nil, nil
+	})
+
+	data := debefix.NewData()
+	data.AddValues(tableUsers,
+		debefix.MapValues{
+			"user_id": 1,
+			"email":   "john@company.com",
+		},
+	)
+
+	queryBuilder := sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{})
+
+	_, err := debefix.Resolve(ctx, data, sql.ResolveFunc(QueryInterfaceMiddleware(a)(qi), queryBuilder))
+	assert.NilError(t, err)
+
+	email, err := a.Value("users", "email", "john@company.com")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []any{email, 1}, queryArgs)
+
+	// the anonymizing query builder forwards the argument field names.
+	_, err = debefix.Resolve(ctx, data, sql.ResolveFunc(qi, NewQueryBuilder(queryBuilder, a)))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"email", "user_id"}, argFieldNames)
+	assert.DeepEqual(t, []any{email, 1}, queryArgs)
+
+	_, err = QueryInterfaceMiddleware(a)(qi).Query(ctx, tableUsers, "SELECT 1", nil, 1)
+	assert.ErrorContains(t, err, "argument field names of query of table 'users' are not available")
+}
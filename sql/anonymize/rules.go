@@ -0,0 +1,148 @@
+package anonymize
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Hash replaces the value with its hash. Strings and byte slices are replaced by the hex-encoded hash truncated to
+// length characters (or the full hash if length is 0), and integers by a positive integer of the same type.
+func Hash(length int) Rule {
+	return func(value any, hash []byte) (any, error) {
+		switch v := value.(type) {
+		case string:
+			return truncateHex(hash, length), nil
+		case []byte:
+			return []byte(truncateHex(hash, length)), nil
+		case int:
+			return int(hashUint(hash) >> 33), nil
+		case int32:
+			return int32(hashUint(hash) >> 33), nil
+		case int64:
+			return int64(hashUint(hash) >> 1), nil
+		default:
+			return nil, fmt.Errorf("unsupported value type %T for hash", v)
+		}
+	}
+}
+
+// Null replaces the value with nil.
+func Null() Rule {
+	return func(value any, hash []byte) (any, error) {
+		return nil, nil
+	}
+}
+
+// Static replaces the value with a fixed value.
+func Static(staticValue any) Rule {
+	return func(value any, hash []byte) (any, error) {
+		return staticValue, nil
+	}
+}
+
+// FakeName replaces the value with a fake person name, like "Olivia Santos".
+func FakeName() Rule {
+	return func(value any, hash []byte) (any, error) {
+		return fakeFirstName(hash) + " " + fakeLastName(hash), nil
+	}
+}
+
+// FakeEmail replaces the value with a fake email address in the domain, like "olivia.santos.1234@example.com".
+// If domain is blank, "example.com" is used.
+func FakeEmail(domain string) Rule {
+	if domain == "" {
+		domain = "example.com"
+	}
+	return func(value any, hash []byte) (any, error) {
+		return fmt.Sprintf("%s.%s.%d@%s", strings.ToLower(fakeFirstName(hash)), strings.ToLower(fakeLastName(hash)),
+			binary.BigEndian.Uint16(hash[4:6])%10000, domain), nil
+	}
+}
+
+// KeepFormat replaces each letter of the value with a random letter of the same case, and each digit with a random
+// digit, keeping all other characters. It can be used for values with a fixed format, like phone or document
+// numbers.
+func KeepFormat() Rule {
+	return func(value any, hash []byte) (any, error) {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			return nil, fmt.Errorf("unsupported value type %T for keep format", v)
+		}
+
+		var b strings.Builder
+		for i, r := range s {
+			h := hash[i%len(hash)] ^ byte(i/len(hash))
+			switch {
+			case unicode.IsDigit(r):
+				b.WriteByte('0' + h%10)
+			case unicode.IsUpper(r):
+				b.WriteByte('A' + h%26)
+			case unicode.IsLetter(r):
+				b.WriteByte('a' + h%26)
+			default:
+				b.WriteRune(r)
+			}
+		}
+		if _, ok := value.([]byte); ok {
+			return []byte(b.String()), nil
+		}
+		return b.String(), nil
+	}
+}
+
+// Pseudonym replaces the value with a sequential pseudonym, like "user_1". Equal values always receive the same
+// pseudonym, so the same rule instance should be used for all columns which must be consistent.
+func Pseudonym(prefix string) Rule {
+	var lock sync.Mutex
+	pseudonyms := map[string]string{}
+	return func(value any, hash []byte) (any, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		key := string(hash)
+		if p, ok := pseudonyms[key]; ok {
+			return p, nil
+		}
+		p := fmt.Sprintf("%s_%d", prefix, len(pseudonyms)+1)
+		pseudonyms[key] = p
+		return p, nil
+	}
+}
+
+func truncateHex(hash []byte, length int) string {
+	ret := hex.EncodeToString(hash)
+	if length > 0 && length < len(ret) {
+		return ret[:length]
+	}
+	return ret
+}
+
+func hashUint(hash []byte) uint64 {
+	return binary.BigEndian.Uint64(hash[:8])
+}
+
+func fakeFirstName(hash []byte) string {
+	return firstNames[int(binary.BigEndian.Uint16(hash[0:2]))%len(firstNames)]
+}
+
+func fakeLastName(hash []byte) string {
+	return lastNames[int(binary.BigEndian.Uint16(hash[2:4]))%len(lastNames)]
+}
+
+var firstNames = []string{
+	"Alice", "Bruno", "Carla", "Daniel", "Elena", "Felipe", "Grace", "Hugo", "Isabel", "James", "Karen", "Lucas",
+	"Maria", "Noah", "Olivia", "Pedro", "Quinn", "Rafael", "Sofia", "Thomas", "Ursula", "Victor", "Wendy", "Yuri",
+}
+
+var lastNames = []string{
+	"Almeida", "Brown", "Costa", "Davis", "Evans", "Ferreira", "Garcia", "Hughes", "Ito", "Johnson", "Kim", "Lopez",
+	"Martins", "Nguyen", "Oliveira", "Park", "Rossi", "Santos", "Smith", "Tanaka", "Walker", "Young",
+}
@@ -29,21 +29,35 @@ type QueryBuilderPlaceholderProvider interface {
 	Next() (placeholder string, argName string)
 }
 
+// QueryBuilderArgFieldNames is an optional interface for QueryBuilder, which also returns the field name of each
+// argument.
+type QueryBuilderArgFieldNames interface {
+	BuildSQLArgFieldNames(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+		returnFieldNames map[string]debefix.ResolveValue) (query string, args []any, argFieldNames []string, err error)
+}
+
 // BuildQuery builds a query string and arguments.
 func BuildQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo, fields map[string]any,
 	returnFieldNames map[string]debefix.ResolveValue) (string, []any, error) {
+	query, args, _, err := BuildQueryArgFieldNames(dialect, resolveInfo, fields, returnFieldNames)
+	return query, args, err
+}
+
+// BuildQueryArgFieldNames builds a query string and arguments, also returning the field name of each argument.
+func BuildQueryArgFieldNames(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo, fields map[string]any,
+	returnFieldNames map[string]debefix.ResolveValue) (string, []any, []string, error) {
 	switch resolveInfo.Type {
 	case debefix.ResolveTypeAdd:
 		return buildInsertQuery(dialect, resolveInfo, fields, returnFieldNames)
 	case debefix.ResolveTypeUpdate:
 		return buildUpdateQuery(dialect, resolveInfo, fields, returnFieldNames)
 	default:
-		return "", nil, fmt.Errorf("unknown resolve type: %v", resolveInfo.Type)
+		return "", nil, nil, fmt.Errorf("unknown resolve type: %v", resolveInfo.Type)
 	}
 }
 
 func buildInsertQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo, fields map[string]any,
-	returnFields map[string]debefix.ResolveValue) (string, []any, error) {
+	returnFields map[string]debefix.ResolveValue) (string, []any, []string, error) {
 	tn := dialect.QuoteTable(resolveInfo.TableID.TableName())
 
 	placeholderProvider := dialect.NewPlaceholderProvider()
@@ -64,7 +78,7 @@ func buildInsertQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		placeholders = append(placeholders, placeholder)
 		fv, ok := fields[fn]
 		if !ok {
-			return "", nil, fmt.Errorf("field %s is not set", fn)
+			return "", nil, nil, fmt.Errorf("field %s is not set", fn)
 		}
 		if argName != "" {
 			args = append(args, sql.Named(argName, fv))
@@ -73,6 +87,7 @@ func buildInsertQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		}
 	}
 
+	argFieldNames := slices.Clone(fieldNames)
 	fieldNames = sliceMapFunc(fieldNames, func(s string) string { return dialect.QuoteField(s) })
 	returnFieldNames = sliceMapFunc(returnFieldNames, func(s string) string { return dialect.QuoteField(s) })
 
@@ -86,11 +101,11 @@ func buildInsertQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		query += fmt.Sprintf(" RETURNING %s", strings.Join(returnFieldNames, ","))
 	}
 
-	return query, args, nil
+	return query, args, argFieldNames, nil
 }
 
 func buildUpdateQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo, fields map[string]any,
-	returnFields map[string]debefix.ResolveValue) (string, []any, error) {
+	returnFields map[string]debefix.ResolveValue) (string, []any, []string, error) {
 	tn := dialect.QuoteTable(resolveInfo.TableID.TableName())
 
 	placeholderProvider := dialect.NewPlaceholderProvider()
@@ -113,7 +128,7 @@ func buildUpdateQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 	}
 
 	if len(keyFieldNames) == 0 {
-		return "", nil, fmt.Errorf("no key fields found for update in '%s'", resolveInfo.TableID.TableID())
+		return "", nil, nil, fmt.Errorf("no key fields found for update in '%s'", resolveInfo.TableID.TableID())
 	}
 
 	slices.Sort(keyFieldNames)
@@ -125,7 +140,7 @@ func buildUpdateQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		placeholders = append(placeholders, placeholder)
 		fv, ok := fields[fn]
 		if !ok {
-			return "", nil, fmt.Errorf("field %s is not set", fn)
+			return "", nil, nil, fmt.Errorf("field %s is not set", fn)
 		}
 		if argName != "" {
 			args = append(args, sql.Named(argName, fv))
@@ -138,7 +153,7 @@ func buildUpdateQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		keyFieldPlaceholders = append(keyFieldPlaceholders, placeholder)
 		fv, ok := fields[fn]
 		if !ok {
-			return "", nil, fmt.Errorf("field %s is not set", fn)
+			return "", nil, nil, fmt.Errorf("field %s is not set", fn)
 		}
 		if argName != "" {
 			args = append(args, sql.Named(argName, fv))
@@ -147,6 +162,7 @@ func buildUpdateQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		}
 	}
 
+	argFieldNames := slices.Concat(fieldNames, keyFieldNames)
 	fieldNames = sliceMapFunc(fieldNames, func(s string) string { return dialect.QuoteField(s) })
 	keyFieldNames = sliceMapFunc(keyFieldNames, func(s string) string { return dialect.QuoteField(s) })
 	returnFieldNames = sliceMapFunc(returnFieldNames, func(s string) string { return dialect.QuoteField(s) })
@@ -170,9 +186,76 @@ func buildUpdateQuery(dialect QueryBuilderDialect, resolveInfo db.ResolveDBInfo,
 		query += fmt.Sprintf(" RETURNING %s", strings.Join(returnFieldNames, ","))
 	}
 
+	return query, args, argFieldNames, nil
+}
+
+// BuildSelectQuery builds a query which selects the fields of the rows where the key fields are equal to their
+// values. A nil key field value is compared using "IS NULL".
+func BuildSelectQuery(dialect QueryBuilderDialect, tableID debefix.TableID, keyFields map[string]any,
+	selectFieldNames []string) (string, []any, error) {
+	tn := dialect.QuoteTable(tableID.TableName())
+
+	if len(keyFields) == 0 {
+		return "", nil, fmt.Errorf("no key fields found for select in '%s'", tableID.TableID())
+	}
+
+	selectFieldNames = slices.Sorted(slices.Values(selectFieldNames))
+
+	where, args := buildKeyWhere(dialect, keyFields)
+
+	selectFields := "*"
+	if len(selectFieldNames) > 0 {
+		selectFields = strings.Join(sliceMapFunc(selectFieldNames, func(s string) string {
+			return dialect.QuoteField(s)
+		}), ", ")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		selectFields,
+		tn,
+		where,
+	)
+
 	return query, args, nil
 }
 
+// BuildDeleteQuery builds a query which deletes the rows where the key fields are equal to their values. A nil key
+// field value is compared using "IS NULL".
+func BuildDeleteQuery(dialect QueryBuilderDialect, tableID debefix.TableID, keyFields map[string]any) (string, []any, error) {
+	tn := dialect.QuoteTable(tableID.TableName())
+
+	if len(keyFields) == 0 {
+		return "", nil, fmt.Errorf("no key fields found for delete in '%s'", tableID.TableID())
+	}
+
+	where, args := buildKeyWhere(dialect, keyFields)
+
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", tn, where), args, nil
+}
+
+// buildKeyWhere builds a where condition comparing the key fields to their values.
+func buildKeyWhere(dialect QueryBuilderDialect, keyFields map[string]any) (string, []any) {
+	placeholderProvider := dialect.NewPlaceholderProvider()
+
+	var whereFields []string
+	var args []any
+	for _, fn := range slices.Sorted(maps.Keys(keyFields)) {
+		fv := keyFields[fn]
+		if fv == nil {
+			whereFields = append(whereFields, fmt.Sprintf("%s IS NULL", dialect.QuoteField(fn)))
+			continue
+		}
+		placeholder, argName := placeholderProvider.Next()
+		whereFields = append(whereFields, fmt.Sprintf("%s = %s", dialect.QuoteField(fn), placeholder))
+		if argName != "" {
+			args = append(args, sql.Named(argName, fv))
+		} else {
+			args = append(args, fv)
+		}
+	}
+	return strings.Join(whereFields, " AND "), args
+}
+
 // NewQueryBuilder returns a QueryBuilder which uses the passed database dialect.
 func NewQueryBuilder(dialect QueryBuilderDialect) QueryBuilder {
 	return &queryBuilder{Dialect: dialect}
@@ -187,6 +270,11 @@ func (b queryBuilder) BuildSQL(ctx context.Context, resolveInfo db.ResolveDBInfo
 	return BuildQuery(b.Dialect, resolveInfo, fields, returnFieldNames)
 }
 
+func (b queryBuilder) BuildSQLArgFieldNames(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+	returnFieldNames map[string]debefix.ResolveValue) (string, []any, []string, error) {
+	return BuildQueryArgFieldNames(b.Dialect, resolveInfo, fields, returnFieldNames)
+}
+
 // DefaultQueryBuilderDialect returns placeholders using ? and unquoted table and field names.
 type DefaultQueryBuilderDialect struct {
 }
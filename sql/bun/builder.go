@@ -0,0 +1,55 @@
+package bun
+
+import (
+	"strings"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/mysql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix-db/v2/sql/sqlite"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// QueryBuilderDialect returns a QueryBuilderDialect for the bun database dialect.
+// The placeholders are always "?", as bun formats the query arguments using its own dialect.
+func QueryBuilderDialect(db bun.IDB) sql.QueryBuilderDialect {
+	var quoteDialect sql.QueryBuilderDialect
+	switch db.Dialect().Name() {
+	case dialect.PG:
+		quoteDialect = postgres.QueryBuilderDialect{}
+	case dialect.MySQL:
+		quoteDialect = mysql.QueryBuilderDialect{}
+	case dialect.SQLite:
+		quoteDialect = sqlite.QueryBuilderDialect{}
+	default:
+		quoteDialect = identQuoteDialect{quote: string(db.Dialect().IdentQuote())}
+	}
+	return queryBuilderDialect{QueryBuilderDialect: quoteDialect}
+}
+
+type queryBuilderDialect struct {
+	sql.QueryBuilderDialect
+}
+
+func (d queryBuilderDialect) NewPlaceholderProvider() sql.QueryBuilderPlaceholderProvider {
+	return sql.DefaultQueryBuilderDialect{}.NewPlaceholderProvider()
+}
+
+// identQuoteDialect quotes identifiers using the bun dialect quote character.
+type identQuoteDialect struct {
+	sql.DefaultQueryBuilderDialect
+	quote string
+}
+
+func (d identQuoteDialect) QuoteTable(tableName string) string {
+	return d.quoteIdentifier(tableName)
+}
+
+func (d identQuoteDialect) QuoteField(fieldName string) string {
+	return d.quoteIdentifier(fieldName)
+}
+
+func (d identQuoteDialect) quoteIdentifier(s string) string {
+	return d.quote + strings.ReplaceAll(s, d.quote, d.quote+d.quote) + d.quote
+}
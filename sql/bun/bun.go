@@ -0,0 +1,12 @@
+package bun
+
+import (
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/uptrace/bun"
+)
+
+// NewQueryInterface returns a QueryInterface for the passed bun database, connection or transaction.
+// Queries are executed using bun, so the query hooks are called and the arguments are formatted by it.
+func NewQueryInterface(db bun.IDB) sql.QueryInterface {
+	return sql.NewSQLQueryInterface(db)
+}
@@ -0,0 +1,32 @@
+module github.com/rrgmc/debefix-db/sql/bun/v2
+
+go 1.23
+
+require (
+	github.com/glebarez/go-sqlite v1.21.2
+	github.com/rrgmc/debefix-db/v2 v2.1.0
+	github.com/rrgmc/debefix/v2 v2.0.6
+	github.com/uptrace/bun v1.2.8
+	github.com/uptrace/bun/dialect/sqlitedialect v1.2.8
+	gotest.tools/v3 v3.5.1
+)
+
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/puzpuzpuz/xsync/v3 v3.4.0 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	github.com/tmthrgd/go-hex v0.0.0-20190904060850-447a3041c3bc // indirect
+	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	modernc.org/libc v1.22.5 // indirect
+	modernc.org/mathutil v1.5.0 // indirect
+	modernc.org/memory v1.5.0 // indirect
+	modernc.org/sqlite v1.23.1 // indirect
+)
+
+replace github.com/rrgmc/debefix-db/v2 => ../..
@@ -0,0 +1,11 @@
+package bun
+
+import (
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/uptrace/bun"
+)
+
+// QueryBuilder returns a sql.QueryBuilder for the bun database dialect.
+func QueryBuilder(db bun.IDB) sql.QueryBuilder {
+	return sql.NewQueryBuilder(QueryBuilderDialect(db))
+}
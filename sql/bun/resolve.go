@@ -0,0 +1,16 @@
+package bun
+
+import (
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"github.com/uptrace/bun"
+)
+
+func ResolveDBFunc(bdb bun.IDB) db.ResolveDBCallback {
+	return sql.ResolveDBFunc(NewQueryInterface(bdb), QueryBuilder(bdb))
+}
+
+func ResolveFunc(bdb bun.IDB) debefix.ResolveCallback {
+	return db.ResolveFunc(ResolveDBFunc(bdb))
+}
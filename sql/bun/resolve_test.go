@@ -0,0 +1,64 @@
+package bun
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/rrgmc/debefix/v2"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags     = debefix.TableName("tags")
+	tablePostTags = debefix.TableName("post_tags")
+)
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, err := sql.Open("sqlite", ":memory:")
+	assert.NilError(t, err)
+	sqldb.SetMaxOpenConns(1)
+	bdb := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = bdb.Close() })
+
+	_, err = bdb.ExecContext(ctx, `CREATE TABLE tags (tag_id INTEGER PRIMARY KEY AUTOINCREMENT, tag_name TEXT NOT NULL)`)
+	assert.NilError(t, err)
+	_, err = bdb.ExecContext(ctx, `CREATE TABLE post_tags (post_id INTEGER NOT NULL, tag_id INTEGER NOT NULL)`)
+	assert.NilError(t, err)
+
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("all"),
+			"tag_name": "All",
+		},
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("half"),
+			"tag_name": "Half",
+		},
+	)
+
+	data.AddValues(tablePostTags,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "half", "tag_id"),
+		},
+	)
+
+	_, err = debefix.Resolve(ctx, data, ResolveFunc(bdb))
+	assert.NilError(t, err)
+
+	var tagIDs []int64
+	assert.NilError(t, bdb.NewSelect().Table("post_tags").Column("tag_id").Scan(ctx, &tagIDs))
+	assert.DeepEqual(t, []int64{2}, tagIDs)
+
+	assert.Equal(t, `"tags"`, QueryBuilderDialect(bdb).QuoteTable("tags"))
+}
@@ -0,0 +1,306 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Cassette is a list of recorded query interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is one recorded query.
+type Interaction struct {
+	TableID          string           `json:"table_id" yaml:"table_id"`
+	Query            string           `json:"query" yaml:"query"`
+	Args             []Value          `json:"args,omitempty" yaml:"args,omitempty"`
+	ReturnFieldNames []string         `json:"return_field_names,omitempty" yaml:"return_field_names,omitempty"`
+	Returned         map[string]Value `json:"returned,omitempty" yaml:"returned,omitempty"`
+	Error            string           `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Codec encodes and decodes cassettes in a file format.
+type Codec interface {
+	Encode(w io.Writer, cassette *Cassette) error
+	Decode(r io.Reader, cassette *Cassette) error
+}
+
+// JSONCodec is a Codec for the JSON format, the default one.
+type JSONCodec struct{}
+
+var _ Codec = JSONCodec{}
+
+func (JSONCodec) Encode(w io.Writer, cassette *Cassette) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cassette)
+}
+
+func (JSONCodec) Decode(r io.Reader, cassette *Cassette) error {
+	return json.NewDecoder(r).Decode(cassette)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+)
+
+// RegisterCodec sets the Codec used by Load and Save for files with the extension, like ".yaml". Files with
+// extensions without a registered codec use JSONCodec.
+func RegisterCodec(extension string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[strings.ToLower(extension)] = codec
+}
+
+// fileCodec returns the codec of the file extension.
+func fileCodec(filename string) Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if codec, ok := codecs[strings.ToLower(filepath.Ext(filename))]; ok {
+		return codec
+	}
+	return JSONCodec{}
+}
+
+// Load loads a cassette from a file, in the format of the Codec registered for its extension, or JSON.
+func Load(filename string) (*Cassette, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCodec(f, fileCodec(filename))
+}
+
+// Read reads a cassette in JSON format.
+func Read(r io.Reader) (*Cassette, error) {
+	return ReadCodec(r, JSONCodec{})
+}
+
+// ReadCodec reads a cassette in the format of the codec.
+func ReadCodec(r io.Reader, codec Codec) (*Cassette, error) {
+	var ret Cassette
+	if err := codec.Decode(r, &ret); err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	return &ret, nil
+}
+
+// Save saves the cassette to a file, in the format of the Codec registered for its extension, or JSON, creating
+// the directory if needed.
+func (c *Cassette) Save(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := c.WriteCodec(f, fileCodec(filename)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes the cassette in JSON format.
+func (c *Cassette) Write(w io.Writer) error {
+	return c.WriteCodec(w, JSONCodec{})
+}
+
+// WriteCodec writes the cassette in the format of the codec.
+func (c *Cassette) WriteCodec(w io.Writer, codec Codec) error {
+	return codec.Encode(w, c)
+}
+
+// Recorder is a sql.QueryInterface which records all the queries executed by another QueryInterface.
+type Recorder struct {
+	qi sql.QueryInterface
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+var _ sql.QueryInterface = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder which executes the queries using qi.
+func NewRecorder(qi sql.QueryInterface) *Recorder {
+	return &Recorder{qi: qi}
+}
+
+func (r *Recorder) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string,
+	args ...any) (map[string]any, error) {
+	ret, err := r.qi.Query(ctx, tableID, query, returnFieldNames, args...)
+
+	interaction := Interaction{
+		TableID:          tableID.TableID(),
+		Query:            query,
+		Args:             encodeArgs(args),
+		ReturnFieldNames: slices.Sorted(slices.Values(returnFieldNames)),
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else if ret != nil {
+		interaction.Returned = map[string]Value{}
+		for fn, fv := range ret {
+			interaction.Returned[fn] = EncodeValue(fv)
+		}
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	return ret, err
+}
+
+// Cassette returns a cassette with the recorded interactions.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: slices.Clone(r.cassette.Interactions)}
+}
+
+// Save saves the recorded interactions to a file, using Cassette.Save.
+func (r *Recorder) Save(filename string) error {
+	return r.Cassette().Save(filename)
+}
+
+// Replayer is a sql.QueryInterface which replays the interactions of a cassette, in order. It returns an error if
+// the executed queries are different from the recorded ones.
+type Replayer struct {
+	cassette        *Cassette
+	compareTimeArgs bool
+
+	mu   sync.Mutex
+	next int
+}
+
+var _ sql.QueryInterface = (*Replayer)(nil)
+
+// NewReplayer creates a Replayer for a cassette.
+func NewReplayer(cassette *Cassette, options ...ReplayerOption) *Replayer {
+	ret := &Replayer{cassette: cassette}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// ReplayerOption is an option for NewReplayer.
+type ReplayerOption func(r *Replayer)
+
+// WithCompareTimeArgs sets whether the values of time arguments are compared. By default only their types are
+// compared, as fixtures usually have times relative to the resolve base time.
+func WithCompareTimeArgs(compareTimeArgs bool) ReplayerOption {
+	return func(r *Replayer) {
+		r.compareTimeArgs = compareTimeArgs
+	}
+}
+
+func (r *Replayer) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string,
+	args ...any) (map[string]any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.cassette.Interactions) {
+		return nil, fmt.Errorf("cassette has no more interactions for query `%s`", query)
+	}
+	interaction := r.cassette.Interactions[r.next]
+	r.next++
+
+	if interaction.TableID != tableID.TableID() {
+		return nil, fmt.Errorf("interaction %d: expected table '%s' but got '%s'", r.next, interaction.TableID,
+			tableID.TableID())
+	}
+	if interaction.Query != query {
+		return nil, fmt.Errorf("interaction %d: expected query `%s` but got `%s`", r.next, interaction.Query, query)
+	}
+	if !slices.Equal(interaction.ReturnFieldNames, slices.Sorted(slices.Values(returnFieldNames))) {
+		return nil, fmt.Errorf("interaction %d: expected returned fields %v but got %v", r.next,
+			interaction.ReturnFieldNames, returnFieldNames)
+	}
+	encodedArgs := encodeArgs(args)
+	if len(encodedArgs) != len(interaction.Args) {
+		return nil, fmt.Errorf("interaction %d: expected %d arguments but got %d", r.next, len(interaction.Args),
+			len(encodedArgs))
+	}
+	for i, arg := range encodedArgs {
+		expected := interaction.Args[i]
+		if arg.Type == TypeTime && expected.Type == TypeTime && !r.compareTimeArgs {
+			continue
+		}
+		if arg != expected {
+			return nil, fmt.Errorf("interaction %d: expected argument %d to be %s '%s' but got %s '%s'", r.next,
+				i+1, expected.Type, expected.Value, arg.Type, arg.Value)
+		}
+	}
+
+	if interaction.Error != "" {
+		return nil, fmt.Errorf("recorded error: %s", interaction.Error)
+	}
+
+	if interaction.Returned == nil {
+		return nil, nil
+	}
+	ret := map[string]any{}
+	for fn, fv := range interaction.Returned {
+		value, err := fv.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("interaction %d: error decoding returned field '%s': %w", r.next, fn, err)
+		}
+		ret[fn] = value
+	}
+	return ret, nil
+}
+
+// Done returns an error if not all interactions were replayed.
+func (r *Replayer) Done() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next < len(r.cassette.Interactions) {
+		return fmt.Errorf("%d of %d cassette interactions were not replayed", len(r.cassette.Interactions)-r.next,
+			len(r.cassette.Interactions))
+	}
+	return nil
+}
+
+func encodeArgs(args []any) []Value {
+	var ret []Value
+	for _, arg := range args {
+		ret = append(ret, EncodeValue(arg))
+	}
+	return ret
+}
+
+// Open returns a Recorder which executes the queries using qi if record is true, otherwise a Replayer of the
+// cassette file. The returned finish function must be called after the resolve. It saves the cassette file when
+// recording, or checks that all interactions were replayed.
+func Open(filename string, record bool, qi sql.QueryInterface,
+	options ...ReplayerOption) (sql.QueryInterface, func() error, error) {
+	if record {
+		recorder := NewRecorder(qi)
+		return recorder, func() error {
+			return recorder.Save(filename)
+		}, nil
+	}
+
+	cassette, err := Load(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	replayer := NewReplayer(cassette, options...)
+	return replayer, replayer.Done, nil
+}
@@ -0,0 +1,108 @@
+package cassette
+
+import (
+	"context"
+	dsql "database/sql"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/sqlite"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags  = debefix.TableName("tags")
+	tablePosts = debefix.TableName("posts")
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "tags" ("created_at", "tag_name") VALUES (?, ?) RETURNING "tag_id"`)).
+		WithArgs(dbmock.AnyArg(), "All").
+		WillReturnRows(dbmock.NewRows("tag_id").AddRow(int64(2)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "posts" ("post_id", "tag_id", "title") VALUES (?, ?, ?)`)).
+		WithArgs(1, int64(2), "First post").
+		WillReturnResult(dbmock.NewResult(0, 1))
+
+	// record
+	qi, finish, err := Open(filename, true, sql.NewSQLQueryInterface(db))
+	assert.NilError(t, err)
+
+	recordedTagID := resolveTestData(t, qi)
+	assert.NilError(t, finish())
+	assert.Equal(t, int64(2), recordedTagID)
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	// replay
+	qi, finish, err = Open(filename, false, nil)
+	assert.NilError(t, err)
+
+	replayedTagID := resolveTestData(t, qi)
+	assert.NilError(t, finish())
+	assert.Equal(t, recordedTagID, replayedTagID)
+
+	// replay with divergent data
+	cassette, err := Load(filename)
+	assert.NilError(t, err)
+	data := debefix.NewData()
+	data.AddValues(tableTags, debefix.MapValues{
+		"tag_id":     debefix.ResolveValueResolve(),
+		"tag_name":   "Other",
+		"created_at": debefix.ValueBaseTimeAdd(),
+	})
+	_, err = debefix.Resolve(ctx, data, sqlite.ResolveFunc(NewReplayer(cassette)))
+	assert.ErrorContains(t, err, `interaction 1: expected argument 2 to be string 'All' but got string 'Other'`)
+}
+
+func TestValue(t *testing.T) {
+	for _, value := range []any{
+		nil, "a", []byte{1, 2, 3}, true, 1, int8(2), int16(3), int32(4), int64(5), uint(6), uint8(7), uint16(8),
+		uint32(9), uint64(10), float32(1.5), 2.5, time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		uuid.MustParse("7d5e5ac4-4d6b-4b0e-9e5d-5f3a0f6b2c11"), dsql.Named("arg", 12),
+	} {
+		decoded, err := EncodeValue(value).Decode()
+		assert.NilError(t, err)
+		assert.Assert(t, reflect.DeepEqual(value, decoded), "expected %#v, got %#v", value, decoded)
+	}
+}
+
+func resolveTestData(t *testing.T, qi sql.QueryInterface) any {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":     debefix.ResolveValueResolve(),
+			"_refid":     debefix.SetValueRefID("all"),
+			"tag_name":   "All",
+			"created_at": debefix.ValueBaseTimeAdd(),
+		},
+	)
+
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "all", "tag_id"),
+			"title":   "First post",
+		},
+	)
+
+	resolved, err := debefix.Resolve(context.Background(), data, sqlite.ResolveFunc(qi))
+	assert.NilError(t, err)
+
+	tagID, err := resolved.FindRefIDRowValue(debefix.ValueRefID(tableTags, "all", "tag_id"))
+	assert.NilError(t, err)
+	return tagID
+}
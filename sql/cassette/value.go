@@ -0,0 +1,154 @@
+package cassette
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Value is a typed value stored in a cassette, so the values are replayed with the same Go type returned by the
+// database driver.
+type Value struct {
+	Type  string `json:"type" yaml:"type"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`   // the argument name of [sql.NamedArg] values.
+	Value string `json:"value,omitempty" yaml:"value,omitempty"` // bytes are stored in base64.
+}
+
+// Value types.
+const (
+	TypeNull    = "null"
+	TypeString  = "string"
+	TypeBytes   = "bytes"
+	TypeBool    = "bool"
+	TypeInt     = "int"
+	TypeInt8    = "int8"
+	TypeInt16   = "int16"
+	TypeInt32   = "int32"
+	TypeInt64   = "int64"
+	TypeUint    = "uint"
+	TypeUint8   = "uint8"
+	TypeUint16  = "uint16"
+	TypeUint32  = "uint32"
+	TypeUint64  = "uint64"
+	TypeFloat32 = "float32"
+	TypeFloat64 = "float64"
+	TypeTime    = "time"
+	TypeUUID    = "uuid"
+	TypeOther   = "other" // an unsupported type, stored using its string representation and replayed as a string.
+)
+
+// EncodeValue encodes a Go value.
+func EncodeValue(value any) Value {
+	switch v := value.(type) {
+	case nil:
+		return Value{Type: TypeNull}
+	case sql.NamedArg:
+		ret := EncodeValue(v.Value)
+		ret.Name = v.Name
+		return ret
+	case string:
+		return Value{Type: TypeString, Value: v}
+	case []byte:
+		return Value{Type: TypeBytes, Value: base64.StdEncoding.EncodeToString(v)}
+	case bool:
+		return Value{Type: TypeBool, Value: strconv.FormatBool(v)}
+	case int:
+		return Value{Type: TypeInt, Value: strconv.FormatInt(int64(v), 10)}
+	case int8:
+		return Value{Type: TypeInt8, Value: strconv.FormatInt(int64(v), 10)}
+	case int16:
+		return Value{Type: TypeInt16, Value: strconv.FormatInt(int64(v), 10)}
+	case int32:
+		return Value{Type: TypeInt32, Value: strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return Value{Type: TypeInt64, Value: strconv.FormatInt(v, 10)}
+	case uint:
+		return Value{Type: TypeUint, Value: strconv.FormatUint(uint64(v), 10)}
+	case uint8:
+		return Value{Type: TypeUint8, Value: strconv.FormatUint(uint64(v), 10)}
+	case uint16:
+		return Value{Type: TypeUint16, Value: strconv.FormatUint(uint64(v), 10)}
+	case uint32:
+		return Value{Type: TypeUint32, Value: strconv.FormatUint(uint64(v), 10)}
+	case uint64:
+		return Value{Type: TypeUint64, Value: strconv.FormatUint(v, 10)}
+	case float32:
+		return Value{Type: TypeFloat32, Value: strconv.FormatFloat(float64(v), 'g', -1, 32)}
+	case float64:
+		return Value{Type: TypeFloat64, Value: strconv.FormatFloat(v, 'g', -1, 64)}
+	case time.Time:
+		return Value{Type: TypeTime, Value: v.Format(time.RFC3339Nano)}
+	case uuid.UUID:
+		return Value{Type: TypeUUID, Value: v.String()}
+	default:
+		return Value{Type: TypeOther, Value: fmt.Sprint(v)}
+	}
+}
+
+// Decode returns the Go value.
+func (v Value) Decode() (any, error) {
+	ret, err := v.decode()
+	if err != nil {
+		return nil, fmt.Errorf("error decoding value of type '%s': %w", v.Type, err)
+	}
+	if v.Name != "" {
+		return sql.Named(v.Name, ret), nil
+	}
+	return ret, nil
+}
+
+func (v Value) decode() (any, error) {
+	switch v.Type {
+	case TypeNull:
+		return nil, nil
+	case TypeString, TypeOther:
+		return v.Value, nil
+	case TypeBytes:
+		return base64.StdEncoding.DecodeString(v.Value)
+	case TypeBool:
+		return strconv.ParseBool(v.Value)
+	case TypeInt:
+		i, err := strconv.ParseInt(v.Value, 10, 0)
+		return int(i), err
+	case TypeInt8:
+		i, err := strconv.ParseInt(v.Value, 10, 8)
+		return int8(i), err
+	case TypeInt16:
+		i, err := strconv.ParseInt(v.Value, 10, 16)
+		return int16(i), err
+	case TypeInt32:
+		i, err := strconv.ParseInt(v.Value, 10, 32)
+		return int32(i), err
+	case TypeInt64:
+		return strconv.ParseInt(v.Value, 10, 64)
+	case TypeUint:
+		i, err := strconv.ParseUint(v.Value, 10, 0)
+		return uint(i), err
+	case TypeUint8:
+		i, err := strconv.ParseUint(v.Value, 10, 8)
+		return uint8(i), err
+	case TypeUint16:
+		i, err := strconv.ParseUint(v.Value, 10, 16)
+		return uint16(i), err
+	case TypeUint32:
+		i, err := strconv.ParseUint(v.Value, 10, 32)
+		return uint32(i), err
+	case TypeUint64:
+		return strconv.ParseUint(v.Value, 10, 64)
+	case TypeFloat32:
+		f, err := strconv.ParseFloat(v.Value, 32)
+		return float32(f), err
+	case TypeFloat64:
+		return strconv.ParseFloat(v.Value, 64)
+	case TypeTime:
+		return time.Parse(time.RFC3339Nano, v.Value)
+	case TypeUUID:
+		return uuid.Parse(v.Value)
+	default:
+		return nil, fmt.Errorf("unknown value type")
+	}
+}
@@ -0,0 +1,33 @@
+// Package yaml is a cassette.Codec for the YAML format. Importing it registers the codec for the ".yaml" and
+// ".yml" file extensions, so cassette.Load and cassette.Save use it for these files.
+package yaml
+
+import (
+	"io"
+
+	"github.com/rrgmc/debefix-db/v2/sql/cassette"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+func init() {
+	cassette.RegisterCodec(".yaml", Codec{})
+	cassette.RegisterCodec(".yml", Codec{})
+}
+
+// Codec is a cassette.Codec for the YAML format.
+type Codec struct{}
+
+var _ cassette.Codec = Codec{}
+
+func (Codec) Encode(w io.Writer, c *cassette.Cassette) error {
+	enc := yamlv3.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (Codec) Decode(r io.Reader, c *cassette.Cassette) error {
+	return yamlv3.NewDecoder(r).Decode(c)
+}
@@ -0,0 +1,53 @@
+package yaml
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rrgmc/debefix-db/v2/sql/cassette"
+	"gotest.tools/v3/assert"
+)
+
+func TestCodec(t *testing.T) {
+	c := &cassette.Cassette{
+		Interactions: []cassette.Interaction{
+			{
+				TableID: "tags",
+				Query:   `INSERT INTO "tags" ("created_at", "tag_name") VALUES (?, ?) RETURNING "tag_id"`,
+				Args: []cassette.Value{
+					cassette.EncodeValue(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+					cassette.EncodeValue("All"),
+				},
+				ReturnFieldNames: []string{"tag_id"},
+				Returned: map[string]cassette.Value{
+					"tag_id": cassette.EncodeValue(uuid.MustParse("7d5e5ac4-4d6b-4b0e-9e5d-5f3a0f6b2c11")),
+				},
+			},
+			{
+				TableID: "posts",
+				Query:   `INSERT INTO "posts" ("title") VALUES (?)`,
+				Args:    []cassette.Value{cassette.EncodeValue([]byte("First post"))},
+				Error:   "insert failed",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NilError(t, c.WriteCodec(&buf, Codec{}))
+	assert.Assert(t, strings.HasPrefix(buf.String(), "interactions:\n  - table_id: tags\n"), buf.String())
+
+	decoded, err := cassette.ReadCodec(&buf, Codec{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, decoded)
+
+	// the codec is registered for the file extension.
+	filename := filepath.Join(t.TempDir(), "cassette.yaml")
+	assert.NilError(t, c.Save(filename))
+	loaded, err := cassette.Load(filename)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, loaded)
+}
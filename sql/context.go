@@ -0,0 +1,29 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/rrgmc/debefix-db/v2"
+)
+
+// QueryInfo is the information about the query being executed, set in the context passed to QueryInterface.Query
+// by ResolveDBFunc.
+type QueryInfo struct {
+	ResolveDBInfo db.ResolveDBInfo
+	// ArgFieldNames is the field name of each query argument. It is only set if the QueryBuilder implements
+	// QueryBuilderArgFieldNames.
+	ArgFieldNames []string
+}
+
+type queryInfoContextKey struct{}
+
+// ContextWithQueryInfo returns a context with the query information.
+func ContextWithQueryInfo(ctx context.Context, queryInfo QueryInfo) context.Context {
+	return context.WithValue(ctx, queryInfoContextKey{}, queryInfo)
+}
+
+// QueryInfoFromContext returns the query information from the context, if available.
+func QueryInfoFromContext(ctx context.Context) (QueryInfo, bool) {
+	queryInfo, ok := ctx.Value(queryInfoContextKey{}).(QueryInfo)
+	return queryInfo, ok
+}
@@ -7,25 +7,52 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/rrgmc/debefix/v2"
 )
 
-// NewDebugQueryInterface returns a QueryInterface that outputs the generated queries.
+// NewDebugQueryInterface returns a QueryInterface that outputs the generated queries, without executing them.
+// The returned fields are simulated using QueryInterfaceCheck.
 // If out is nil, [os.Stdout] will be used.
 func NewDebugQueryInterface(out io.Writer) QueryInterface {
+	return DebugMiddleware(out)(QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		return QueryInterfaceCheck(ctx, query, returnFieldNames, args...)
+	}))
+}
+
+// DebugMiddleware returns a QueryInterfaceMiddleware that outputs the queries before executing them.
+// If out is nil, [os.Stdout] will be used.
+func DebugMiddleware(out io.Writer) QueryInterfaceMiddleware {
 	if out == nil {
 		out = os.Stdout
 	}
-	return &debugQueryInterface{out: out}
+	return func(next QueryInterface) QueryInterface {
+		return &debugQueryInterface{out: out, next: next}
+	}
 }
 
 type debugQueryInterface struct {
-	out         io.Writer
+	out  io.Writer
+	next QueryInterface
+
+	mu          sync.Mutex // serializes the output, as queries may be executed concurrently, like by db.ResolveParallel.
 	lastTableID debefix.TableID
 }
 
 func (m *debugQueryInterface) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+	if err := m.output(tableID, query, args); err != nil {
+		return nil, err
+	}
+	return m.next.Query(ctx, tableID, query, returnFieldNames, args...)
+}
+
+// output outputs the query.
+func (m *debugQueryInterface) output(tableID debefix.TableID, query string, args []any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	var retErr error
 	var err error
 
@@ -54,9 +81,5 @@ func (m *debugQueryInterface) Query(ctx context.Context, tableID debefix.TableID
 		retErr = errors.Join(retErr, err)
 	}
 
-	if retErr != nil {
-		return nil, retErr
-	}
-
-	return QueryInterfaceCheck(ctx, query, returnFieldNames, args...)
+	return retErr
 }
@@ -0,0 +1,293 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// Metadata is the database metadata needed to walk the foreign keys.
+type Metadata struct {
+	Tables map[string]*TableInfo // map key is the table name.
+}
+
+// TableInfo is the metadata of one table.
+type TableInfo struct {
+	Name             string // table name, including the schema if needed.
+	PrimaryKey       []string
+	GeneratedColumns []string // columns generated by the database, like identity or serial columns.
+	ForeignKeys      []ForeignKey
+}
+
+// ForeignKey is a foreign key from a table to a referenced table.
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// MetadataProvider returns the database metadata.
+type MetadataProvider interface {
+	Metadata(ctx context.Context) (*Metadata, error)
+}
+
+// Root is a root table query where the extraction starts.
+type Root struct {
+	Table string
+	Where string // WHERE clause, without the "WHERE" keyword. Must use the dialect placeholders.
+	Args  []any
+}
+
+// Result is the result of an extraction.
+type Result struct {
+	Metadata *Metadata
+	Tables   map[string]*Table // map key is the table name.
+}
+
+// Table is the list of extracted rows of a table.
+type Table struct {
+	Name string
+	Rows []map[string]any
+}
+
+// TableNames returns the sorted list of table names.
+func (r *Result) TableNames() []string {
+	return slices.Sorted(maps.Keys(r.Tables))
+}
+
+// Extractor extracts database rows, following foreign keys.
+type Extractor struct {
+	db                dbsql.DB
+	dialect           dbsql.QueryBuilderDialect
+	metadataProvider  MetadataProvider
+	followReferencing bool
+	maxRows           int
+	rowFilters        []RowFilter
+}
+
+// RowFilter can change the values of an extracted row. It is called after all rows were extracted, in table and row
+// order.
+type RowFilter func(ctx context.Context, table string, row map[string]any) error
+
+// NewExtractor creates an Extractor for a database.
+func NewExtractor(db dbsql.DB, dialect dbsql.QueryBuilderDialect, metadataProvider MetadataProvider,
+	options ...Option) *Extractor {
+	ret := &Extractor{
+		db:               db,
+		dialect:          dialect,
+		metadataProvider: metadataProvider,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// Option is an option for NewExtractor.
+type Option func(e *Extractor)
+
+// WithFollowReferencing sets whether rows of other tables which reference the extracted rows are also extracted.
+// By default only the referenced rows are extracted, which are the ones needed to insert the root rows.
+func WithFollowReferencing(followReferencing bool) Option {
+	return func(e *Extractor) {
+		e.followReferencing = followReferencing
+	}
+}
+
+// WithMaxRows sets the maximum amount of extracted rows, returning an error if it is reached.
+func WithMaxRows(maxRows int) Option {
+	return func(e *Extractor) {
+		e.maxRows = maxRows
+	}
+}
+
+// WithRowFilter adds a filter to change the extracted rows, like anonymizing sensitive data.
+func WithRowFilter(rowFilter RowFilter) Option {
+	return func(e *Extractor) {
+		e.rowFilters = append(e.rowFilters, rowFilter)
+	}
+}
+
+// Extract extracts the rows selected by the roots, and all rows connected to them by foreign keys.
+func (e *Extractor) Extract(ctx context.Context, roots ...Root) (*Result, error) {
+	metadata, err := e.metadataProvider.Metadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading metadata: %w", err)
+	}
+
+	ex := &extraction{
+		Extractor: e,
+		result: &Result{
+			Metadata: metadata,
+			Tables:   map[string]*Table{},
+		},
+		seen: map[string]bool{},
+	}
+
+	for _, root := range roots {
+		if err := ex.queryRows(ctx, root.Table, root.Where, root.Args); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(ex.pending) > 0 {
+		item := ex.pending[0]
+		ex.pending = ex.pending[1:]
+		if err := ex.followRow(ctx, item.table, item.row); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tableName := range ex.result.TableNames() {
+		for _, row := range ex.result.Tables[tableName].Rows {
+			for _, filter := range e.rowFilters {
+				if err := filter(ctx, tableName, row); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return ex.result, nil
+}
+
+type extraction struct {
+	*Extractor
+	result  *Result
+	seen    map[string]bool
+	pending []pendingRow
+	count   int
+}
+
+type pendingRow struct {
+	table string
+	row   map[string]any
+}
+
+// followRow queries the rows referenced by the row, and optionally the ones which references it.
+func (ex *extraction) followRow(ctx context.Context, table string, row map[string]any) error {
+	tableInfo := ex.result.Metadata.Tables[table]
+	if tableInfo == nil {
+		return nil
+	}
+
+	for _, fk := range tableInfo.ForeignKeys {
+		if err := ex.queryMatching(ctx, fk.RefTable, fk.RefColumns, fk.Columns, row); err != nil {
+			return err
+		}
+	}
+
+	if ex.followReferencing {
+		for _, refTableName := range slices.Sorted(maps.Keys(ex.result.Metadata.Tables)) {
+			for _, fk := range ex.result.Metadata.Tables[refTableName].ForeignKeys {
+				if fk.RefTable != table {
+					continue
+				}
+				if err := ex.queryMatching(ctx, refTableName, fk.Columns, fk.RefColumns, row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryMatching queries the rows of the table where the columns are equal to the source row columns.
+func (ex *extraction) queryMatching(ctx context.Context, table string, columns []string, sourceColumns []string,
+	sourceRow map[string]any) error {
+	placeholderProvider := ex.dialect.NewPlaceholderProvider()
+	var where []string
+	var args []any
+	for i, column := range columns {
+		value := sourceRow[sourceColumns[i]]
+		if value == nil {
+			return nil
+		}
+		placeholder, argName := placeholderProvider.Next()
+		where = append(where, fmt.Sprintf("%s = %s", ex.dialect.QuoteField(column), placeholder))
+		args = append(args, namedArg(argName, value))
+	}
+	return ex.queryRows(ctx, table, strings.Join(where, " AND "), args)
+}
+
+// quoteTable quotes a table name, which may be qualified by the schema like "public.tags", quoting the schema and
+// table parts separately.
+func (ex *extraction) quoteTable(table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return ex.dialect.QuoteTable(schema) + "." + ex.dialect.QuoteTable(name)
+	}
+	return ex.dialect.QuoteTable(table)
+}
+
+// queryRows queries the table rows, adding the ones not seen yet to the result.
+func (ex *extraction) queryRows(ctx context.Context, table string, where string, args []any) error {
+	query := fmt.Sprintf("SELECT * FROM %s", ex.quoteTable(table))
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	rows, err := ex.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error executing query `%s`: %w", query, err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		values := make([]any, len(cols))
+		pointers := make([]any, len(cols))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		row := map[string]any{}
+		for i, col := range cols {
+			row[col] = values[i]
+		}
+
+		key := ex.rowKey(table, row)
+		if ex.seen[key] {
+			continue
+		}
+		ex.seen[key] = true
+
+		ex.count++
+		if ex.maxRows > 0 && ex.count > ex.maxRows {
+			return fmt.Errorf("maximum of %d extracted rows reached", ex.maxRows)
+		}
+
+		if _, ok := ex.result.Tables[table]; !ok {
+			ex.result.Tables[table] = &Table{Name: table}
+		}
+		ex.result.Tables[table].Rows = append(ex.result.Tables[table].Rows, row)
+		ex.pending = append(ex.pending, pendingRow{table: table, row: row})
+	}
+
+	return rows.Err()
+}
+
+// rowKey returns a key which uniquely identifies the row, using the primary key if available.
+func (ex *extraction) rowKey(table string, row map[string]any) string {
+	columns := slices.Sorted(maps.Keys(row))
+	if tableInfo := ex.result.Metadata.Tables[table]; tableInfo != nil && len(tableInfo.PrimaryKey) > 0 {
+		columns = tableInfo.PrimaryKey
+	}
+	var b strings.Builder
+	b.WriteString(table)
+	for _, column := range columns {
+		_, _ = fmt.Fprintf(&b, "|%v", row[column])
+	}
+	return b.String()
+}
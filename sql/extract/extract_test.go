@@ -0,0 +1,210 @@
+package extract
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestExtract(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t,
+		testQuery{"posts", "post_id", 11},
+		testQuery{"users", "user_id", 1},
+		testQuery{"posts", "post_id", 10},
+		testQuery{"users", "user_id", 1},
+		testQuery{"tags", "tag_id", 1},
+	)
+
+	extractor := NewExtractor(db, dbsql.DefaultQueryBuilderDialect{}, testMetadata)
+
+	result, err := extractor.Extract(ctx, Root{Table: "posts", Where: "post_id = ?", Args: []any{11}})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{"posts", "tags", "users"}, result.TableNames())
+	assert.Equal(t, 2, len(result.Tables["posts"].Rows)) // the root post and its parent.
+	assert.Equal(t, 1, len(result.Tables["tags"].Rows))
+	assert.Equal(t, 1, len(result.Tables["users"].Rows))
+
+	src, err := Generate(result, WithPackageName("fixtures"))
+	assert.NilError(t, err)
+
+	golden.Assert(t, string(src), "extract.go.golden")
+}
+
+func TestExtractFollowReferencing(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t,
+		testQuery{"users", "user_id", 1},
+		testQuery{"posts", "user_id", 1},
+		testQuery{"users", "user_id", 1},
+		testQuery{"tags", "tag_id", 1},
+		testQuery{"comments", "post_id", 10},
+		testQuery{"posts", "parent_post_id", 10},
+		testQuery{"users", "user_id", 1},
+		testQuery{"posts", "post_id", 10},
+		testQuery{"comments", "post_id", 11},
+		testQuery{"posts", "parent_post_id", 11},
+		testQuery{"posts", "tag_id", 1},
+		testQuery{"posts", "post_id", 10},
+		testQuery{"posts", "post_id", 11},
+	)
+
+	extractor := NewExtractor(db, dbsql.DefaultQueryBuilderDialect{}, testMetadata,
+		WithFollowReferencing(true),
+		WithRowFilter(func(ctx context.Context, table string, row map[string]any) error {
+			if table == "users" {
+				row["email"] = "user@example.com"
+			}
+			return nil
+		}))
+
+	result, err := extractor.Extract(ctx, Root{Table: "users", Where: "user_id = ?", Args: []any{1}})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{"comments", "posts", "tags", "users"}, result.TableNames())
+	assert.Equal(t, 2, len(result.Tables["posts"].Rows))
+	assert.Equal(t, 2, len(result.Tables["comments"].Rows))
+	assert.Equal(t, "user@example.com", result.Tables["users"].Rows[0]["email"])
+
+	src, err := Generate(result, WithKeepGeneratedValues(true))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(src), `debefix.ValueRefID(tablePosts, "posts_10", "post_id")`))
+	assert.Assert(t, !strings.Contains(string(src), "ResolveValueResolve"))
+}
+
+func TestExtractMaxRows(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t,
+		testQuery{"posts", "post_id", 11},
+		testQuery{"users", "user_id", 1},
+		testQuery{"posts", "post_id", 10},
+	)
+
+	extractor := NewExtractor(db, dbsql.DefaultQueryBuilderDialect{}, testMetadata, WithMaxRows(2))
+
+	_, err := extractor.Extract(ctx, Root{Table: "posts", Where: "post_id = ?", Args: []any{11}})
+	assert.ErrorContains(t, err, "maximum of 2 extracted rows reached")
+}
+
+var testMetadata = staticMetadataProvider{
+	Tables: map[string]*TableInfo{
+		"tags": {
+			Name:             "tags",
+			PrimaryKey:       []string{"tag_id"},
+			GeneratedColumns: []string{"tag_id"},
+		},
+		"users": {
+			Name:       "users",
+			PrimaryKey: []string{"user_id"},
+		},
+		"posts": {
+			Name:             "posts",
+			PrimaryKey:       []string{"post_id"},
+			GeneratedColumns: []string{"post_id"},
+			ForeignKeys: []ForeignKey{
+				{Name: "posts_user_fk", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"user_id"}},
+				{Name: "posts_tag_fk", Columns: []string{"tag_id"}, RefTable: "tags", RefColumns: []string{"tag_id"}},
+				{Name: "posts_parent_fk", Columns: []string{"parent_post_id"}, RefTable: "posts",
+					RefColumns: []string{"post_id"}},
+			},
+		},
+		"comments": {
+			Name:             "comments",
+			PrimaryKey:       []string{"comment_id"},
+			GeneratedColumns: []string{"comment_id"},
+			ForeignKeys: []ForeignKey{
+				{Name: "comments_post_fk", Columns: []string{"post_id"}, RefTable: "posts",
+					RefColumns: []string{"post_id"}},
+			},
+		},
+	},
+}
+
+type staticMetadataProvider Metadata
+
+func (p staticMetadataProvider) Metadata(ctx context.Context) (*Metadata, error) {
+	ret := Metadata(p)
+	return &ret, nil
+}
+
+// testTables are the rows of the test database, with the value types returned by database drivers.
+var testTables = map[string]struct {
+	columns []string
+	rows    [][]driver.Value
+}{
+	"tags": {
+		columns: []string{"tag_id", "name"},
+		rows: [][]driver.Value{
+			{int64(1), "Go"},
+			{int64(2), "Unused"},
+		},
+	},
+	"users": {
+		columns: []string{"user_id", "name", "email"},
+		rows: [][]driver.Value{
+			{int64(1), "John", "john@example.com"},
+			{int64(2), "Mary", "mary@example.com"},
+		},
+	},
+	"posts": {
+		columns: []string{"post_id", "user_id", "tag_id", "parent_post_id", "title", "score", "created_at"},
+		rows: [][]driver.Value{
+			{int64(10), int64(1), int64(1), nil, "Root post", 1.5, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{int64(11), int64(1), nil, int64(10), `Reply "post"`, nil, time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC)},
+			{int64(12), int64(2), int64(2), nil, "Other post", nil, time.Date(2024, 1, 4, 3, 4, 5, 0, time.UTC)},
+		},
+	},
+	"comments": {
+		columns: []string{"comment_id", "post_id", "body"},
+		rows: [][]driver.Value{
+			{int64(100), int64(10), "Nice"},
+			{int64(101), int64(11), "Thanks"},
+			{int64(102), int64(12), "Other"},
+		},
+	},
+}
+
+// testQuery is an expected query of the rows of a table where a column is equal to a value.
+type testQuery struct {
+	table  string
+	column string
+	value  int64
+}
+
+// openTestDB returns a database which expects the queries in order, returning the matching testTables rows.
+func openTestDB(t *testing.T, queries ...testQuery) *sql.DB {
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		assert.Check(t, mock.ExpectationsWereMet())
+		_ = db.Close()
+	})
+
+	for _, query := range queries {
+		table := testTables[query.table]
+		colIdx := slices.Index(table.columns, query.column)
+		rows := dbmock.NewRows(table.columns...)
+		for _, row := range table.rows {
+			if row[colIdx] == query.value {
+				rows.AddRow(row...)
+			}
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", query.table, query.column))).
+			WithArgs(query.value).
+			WillReturnRows(rows)
+	}
+
+	return db
+}
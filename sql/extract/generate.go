@@ -0,0 +1,332 @@
+package extract
+
+import (
+	"fmt"
+	"go/format"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Generate generates Go source code with a function which returns a [debefix.Data] containing the extracted rows.
+//
+// All rows receive a RefID, and the foreign key fields which reference other extracted rows are generated using
+// [debefix.ValueRefID]. The columns generated by the database are generated as [debefix.ResolveValueResolve],
+// unless WithKeepGeneratedValues is set.
+//
+// Only values of basic Go types and [time.Time] are supported, a RowFilter can be used to convert other types.
+func Generate(result *Result, options ...GenerateOption) ([]byte, error) {
+	optns := generateOptions{
+		packageName: "fixtures",
+		funcName:    "Data",
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	g := &generator{
+		generateOptions: optns,
+		result:          result,
+		tableVars:       map[string]string{},
+		refIDs:          map[string][]string{},
+	}
+	return g.generate()
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// WithPackageName sets the package name of the generated source. The default is "fixtures".
+func WithPackageName(packageName string) GenerateOption {
+	return func(o *generateOptions) {
+		o.packageName = packageName
+	}
+}
+
+// WithFuncName sets the name of the generated function. The default is "Data".
+func WithFuncName(funcName string) GenerateOption {
+	return func(o *generateOptions) {
+		o.funcName = funcName
+	}
+}
+
+// WithKeepGeneratedValues keeps the extracted values of the columns generated by the database, instead of
+// resolving them when the fixture is inserted.
+func WithKeepGeneratedValues(keepGeneratedValues bool) GenerateOption {
+	return func(o *generateOptions) {
+		o.keepGeneratedValues = keepGeneratedValues
+	}
+}
+
+type generateOptions struct {
+	packageName         string
+	funcName            string
+	keepGeneratedValues bool
+}
+
+type generator struct {
+	generateOptions
+	result    *Result
+	tableVars map[string]string   // table name: Go variable name
+	refIDs    map[string][]string // table name: RefID of each row
+	imports   map[string]bool
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.imports = map[string]bool{
+		"github.com/rrgmc/debefix/v2": true,
+	}
+
+	tableNames := g.result.TableNames()
+	for _, tableName := range tableNames {
+		g.tableVars[tableName] = "table" + goName(tableName)
+		g.refIDs[tableName] = g.tableRefIDs(g.result.Tables[tableName])
+	}
+
+	var body strings.Builder
+	for _, tableName := range tableNames {
+		table := g.result.Tables[tableName]
+		_, _ = fmt.Fprintf(&body, "\n\tdata.AddValues(%s,\n", g.tableVars[tableName])
+		for _, rowIdx := range g.rowOrder(table) {
+			if err := g.generateRow(&body, table, rowIdx); err != nil {
+				return nil, err
+			}
+		}
+		body.WriteString("\t)\n")
+	}
+
+	var src strings.Builder
+	src.WriteString("// Code extracted by debefix-db.\n\n")
+	_, _ = fmt.Fprintf(&src, "package %s\n\n", g.packageName)
+
+	// standard library imports first.
+	src.WriteString("import (\n")
+	var stdImports, otherImports []string
+	for _, imp := range slices.Sorted(maps.Keys(g.imports)) {
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			otherImports = append(otherImports, imp)
+		} else {
+			stdImports = append(stdImports, imp)
+		}
+	}
+	for _, imp := range stdImports {
+		_, _ = fmt.Fprintf(&src, "\t%q\n", imp)
+	}
+	if len(stdImports) > 0 {
+		src.WriteString("\n")
+	}
+	for _, imp := range otherImports {
+		_, _ = fmt.Fprintf(&src, "\t%q\n", imp)
+	}
+	src.WriteString(")\n\n")
+
+	if len(tableNames) > 0 {
+		src.WriteString("var (\n")
+		for _, tableName := range tableNames {
+			_, _ = fmt.Fprintf(&src, "\t%s = debefix.TableName(%q)\n", g.tableVars[tableName], tableName)
+		}
+		src.WriteString(")\n\n")
+	}
+
+	_, _ = fmt.Fprintf(&src, "// %s returns the extracted fixture data.\n", g.funcName)
+	_, _ = fmt.Fprintf(&src, "func %s() *debefix.Data {\n\tdata := debefix.NewData()\n", g.funcName)
+	src.WriteString(body.String())
+	src.WriteString("\n\treturn data\n}\n")
+
+	ret, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated source: %w", err)
+	}
+	return ret, nil
+}
+
+// generateRow generates the values of one row.
+func (g *generator) generateRow(out *strings.Builder, table *Table, rowIdx int) error {
+	row := table.Rows[rowIdx]
+	tableInfo := g.result.Metadata.Tables[table.Name]
+
+	values := map[string]string{}
+	for fieldName, fieldValue := range row {
+		if tableInfo != nil && !g.keepGeneratedValues && slices.Contains(tableInfo.GeneratedColumns, fieldName) {
+			values[fieldName] = "debefix.ResolveValueResolve()"
+			continue
+		}
+		literal, err := g.goLiteral(fieldValue)
+		if err != nil {
+			return fmt.Errorf("error generating field '%s' of table '%s': %w", fieldName, table.Name, err)
+		}
+		values[fieldName] = literal
+	}
+
+	if tableInfo != nil {
+		for _, fk := range tableInfo.ForeignKeys {
+			refTable, refIdx, ok := g.findReferenced(fk, row)
+			if !ok {
+				continue
+			}
+			for i, column := range fk.Columns {
+				values[column] = fmt.Sprintf("debefix.ValueRefID(%s, %q, %q)", g.tableVars[refTable.Name],
+					g.refIDs[refTable.Name][refIdx], fk.RefColumns[i])
+			}
+		}
+	}
+
+	out.WriteString("\t\tdebefix.MapValues{\n")
+	_, _ = fmt.Fprintf(out, "\t\t\t\"_refid\": debefix.SetValueRefID(%q),\n", g.refIDs[table.Name][rowIdx])
+	for _, fieldName := range slices.Sorted(maps.Keys(values)) {
+		_, _ = fmt.Fprintf(out, "\t\t\t%q: %s,\n", fieldName, values[fieldName])
+	}
+	out.WriteString("\t\t},\n")
+	return nil
+}
+
+// findReferenced finds the extracted row referenced by the foreign key.
+func (g *generator) findReferenced(fk ForeignKey, row map[string]any) (*Table, int, bool) {
+	refTable, ok := g.result.Tables[fk.RefTable]
+	if !ok {
+		return nil, 0, false
+	}
+	for idx, refRow := range refTable.Rows {
+		match := true
+		for i, column := range fk.Columns {
+			if row[column] == nil || fmt.Sprint(row[column]) != fmt.Sprint(refRow[fk.RefColumns[i]]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return refTable, idx, true
+		}
+	}
+	return nil, 0, false
+}
+
+// rowOrder returns the order to generate the table rows, with the rows referenced by foreign keys to the same table
+// first.
+func (g *generator) rowOrder(table *Table) []int {
+	tableInfo := g.result.Metadata.Tables[table.Name]
+
+	var ret []int
+	added := make([]bool, len(table.Rows))
+	for len(ret) < len(table.Rows) {
+		progress := false
+		for idx, row := range table.Rows {
+			if added[idx] {
+				continue
+			}
+			ready := true
+			if tableInfo != nil {
+				for _, fk := range tableInfo.ForeignKeys {
+					if fk.RefTable != table.Name {
+						continue
+					}
+					if _, refIdx, ok := g.findReferenced(fk, row); ok && refIdx != idx && !added[refIdx] {
+						ready = false
+					}
+				}
+			}
+			if ready {
+				ret = append(ret, idx)
+				added[idx] = true
+				progress = true
+			}
+		}
+		if !progress {
+			// circular references, add the remaining rows in order.
+			for idx := range table.Rows {
+				if !added[idx] {
+					ret = append(ret, idx)
+					added[idx] = true
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// tableRefIDs returns an unique RefID for each row of the table, using the primary key values if available.
+func (g *generator) tableRefIDs(table *Table) []string {
+	var primaryKey []string
+	if tableInfo := g.result.Metadata.Tables[table.Name]; tableInfo != nil {
+		primaryKey = tableInfo.PrimaryKey
+	}
+
+	prefix := table.Name
+	if idx := strings.LastIndex(prefix, "."); idx >= 0 {
+		prefix = prefix[idx+1:]
+	}
+
+	seen := map[string]bool{}
+	var ret []string
+	for idx, row := range table.Rows {
+		var parts []string
+		for _, column := range primaryKey {
+			parts = append(parts, fmt.Sprint(row[column]))
+		}
+		if len(parts) == 0 {
+			parts = append(parts, strconv.Itoa(idx+1))
+		}
+		refID := prefix + "_" + strings.Join(parts, "_")
+		for i := 2; seen[refID]; i++ {
+			refID = fmt.Sprintf("%s_%s_%d", prefix, strings.Join(parts, "_"), i)
+		}
+		seen[refID] = true
+		ret = append(ret, refID)
+	}
+	return ret
+}
+
+// goLiteral returns the Go literal of a value.
+func (g *generator) goLiteral(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "nil", nil
+	case string:
+		return strconv.Quote(v), nil
+	case []byte:
+		if utf8.Valid(v) {
+			return fmt.Sprintf("[]byte(%s)", strconv.Quote(string(v))), nil
+		}
+		return fmt.Sprintf("%#v", v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%T(%d)", v, v), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return "", fmt.Errorf("unsupported float value %v", v)
+		}
+		return fmt.Sprintf("float64(%s)", strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case float32:
+		return fmt.Sprintf("float32(%s)", strconv.FormatFloat(float64(v), 'g', -1, 32)), nil
+	case time.Time:
+		g.imports["time"] = true
+		v = v.UTC()
+		return fmt.Sprintf("time.Date(%d, time.%s, %d, %d, %d, %d, %d, time.UTC)", v.Year(), v.Month(), v.Day(),
+			v.Hour(), v.Minute(), v.Second(), v.Nanosecond()), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// goName converts a table name to an exported Go name, like "public.user_posts" to "PublicUserPosts".
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r, size := utf8.DecodeRuneInString(part)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(part[size:])
+	}
+	return b.String()
+}
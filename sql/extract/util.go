@@ -0,0 +1,11 @@
+package extract
+
+import "database/sql"
+
+// namedArg wraps the value in a [sql.NamedArg] if the placeholder provider returned an argument name.
+func namedArg(argName string, value any) any {
+	if argName != "" {
+		return sql.Named(argName, value)
+	}
+	return value
+}
@@ -0,0 +1,168 @@
+package golangmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// Step is a fixture migration with its version.
+type Step struct {
+	Version    uint
+	Identifier string
+	Migration  *dbsql.Migration
+}
+
+// Source is a golang-migrate [source.Driver] which returns the SQL scripts of fixture migrations. The scripts
+// contain multiple statements, which some database drivers must be configured to support, like the
+// "multiStatements" parameter of mysql.
+// Use it with [github.com/golang-migrate/migrate/v4.NewWithSourceInstance].
+type Source struct {
+	steps map[uint]Step
+	base  source.Driver
+
+	versionsOnce sync.Once
+	versions     []uint
+	versionsErr  error
+}
+
+var _ source.Driver = (*Source)(nil)
+
+// New creates a Source with the steps.
+func New(steps []Step, options ...Option) (*Source, error) {
+	ret := &Source{
+		steps: map[uint]Step{},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	for _, step := range steps {
+		if _, ok := ret.steps[step.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", step.Version)
+		}
+		ret.steps[step.Version] = step
+	}
+	return ret, nil
+}
+
+// Option is an option for New.
+type Option func(*Source)
+
+// WithSource merges the migrations of another source, like the schema migrations, with the fixture migrations,
+// so both can be applied in the same versioned pipeline. The versions must not overlap.
+func WithSource(base source.Driver) Option {
+	return func(s *Source) {
+		s.base = base
+	}
+}
+
+// Open is not supported, use New to create the source.
+func (s *Source) Open(url string) (source.Driver, error) {
+	return nil, errors.New("Open() cannot be called on the debefix source driver, use New")
+}
+
+func (s *Source) Close() error {
+	if s.base != nil {
+		return s.base.Close()
+	}
+	return nil
+}
+
+func (s *Source) First() (version uint, err error) {
+	versions, err := s.loadVersions()
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: "debefix", Err: os.ErrNotExist}
+	}
+	return versions[0], nil
+}
+
+func (s *Source) Prev(version uint) (prevVersion uint, err error) {
+	versions, err := s.loadVersions()
+	if err != nil {
+		return 0, err
+	}
+	idx, ok := slices.BinarySearch(versions, version)
+	if !ok || idx == 0 {
+		return 0, &os.PathError{Op: fmt.Sprintf("prev for version %d", version), Path: "debefix", Err: os.ErrNotExist}
+	}
+	return versions[idx-1], nil
+}
+
+func (s *Source) Next(version uint) (nextVersion uint, err error) {
+	versions, err := s.loadVersions()
+	if err != nil {
+		return 0, err
+	}
+	idx, ok := slices.BinarySearch(versions, version)
+	if !ok || idx == len(versions)-1 {
+		return 0, &os.PathError{Op: fmt.Sprintf("next for version %d", version), Path: "debefix", Err: os.ErrNotExist}
+	}
+	return versions[idx+1], nil
+}
+
+func (s *Source) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
+	if step, ok := s.steps[version]; ok {
+		up, _, err := step.Migration.SQL(context.Background())
+		if err != nil {
+			return nil, "", fmt.Errorf("error building migration %d: %w", version, err)
+		}
+		return io.NopCloser(strings.NewReader(up)), step.Identifier, nil
+	}
+	if s.base != nil {
+		return s.base.ReadUp(version)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read up for version %d", version), Path: "debefix", Err: os.ErrNotExist}
+}
+
+func (s *Source) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
+	if step, ok := s.steps[version]; ok {
+		_, down, err := step.Migration.SQL(context.Background())
+		if err != nil {
+			return nil, "", fmt.Errorf("error building migration %d: %w", version, err)
+		}
+		return io.NopCloser(strings.NewReader(down)), step.Identifier, nil
+	}
+	if s.base != nil {
+		return s.base.ReadDown(version)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read down for version %d", version), Path: "debefix", Err: os.ErrNotExist}
+}
+
+// loadVersions returns the sorted versions of the steps and of the base source.
+func (s *Source) loadVersions() ([]uint, error) {
+	s.versionsOnce.Do(func() {
+		versions := make([]uint, 0, len(s.steps))
+		for version := range s.steps {
+			versions = append(versions, version)
+		}
+		if s.base != nil {
+			version, err := s.base.First()
+			for err == nil {
+				if _, ok := s.steps[version]; ok {
+					s.versionsErr = fmt.Errorf("migration version %d exists in both sources", version)
+					return
+				}
+				versions = append(versions, version)
+				version, err = s.base.Next(version)
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				s.versionsErr = err
+				return
+			}
+		}
+		slices.Sort(versions)
+		s.versions = versions
+	})
+	return s.versions, s.versionsErr
+}
@@ -0,0 +1,94 @@
+package golangmigrate
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestSource(t *testing.T) {
+	base, err := iofs.New(fstest.MapFS{
+		"1_create_roles.up.sql":   {Data: []byte("CREATE TABLE roles (role_id int, name text);")},
+		"1_create_roles.down.sql": {Data: []byte("DROP TABLE roles;")},
+		"3_add_role_level.up.sql": {Data: []byte("ALTER TABLE roles ADD level int;")},
+	}, ".")
+	assert.NilError(t, err)
+
+	data := debefix.NewData()
+	data.AddValues(debefix.TableName("roles"),
+		debefix.MapValues{"role_id": 1, "name": "admin"},
+		debefix.MapValues{"role_id": 2, "name": "user"},
+	)
+
+	src, err := New([]Step{
+		{
+			Version:    2,
+			Identifier: "roles",
+			Migration: dbsql.NewMigration(data, postgres.QueryBuilderDialect{},
+				dbsql.WithMigrationKeyFields(debefix.TableName("roles"), "role_id")),
+		},
+	}, WithSource(base))
+	assert.NilError(t, err)
+
+	version, err := src.First()
+	assert.NilError(t, err)
+	assert.Equal(t, uint(1), version)
+	version, err = src.Next(version)
+	assert.NilError(t, err)
+	assert.Equal(t, uint(2), version)
+	version, err = src.Next(version)
+	assert.NilError(t, err)
+	assert.Equal(t, uint(3), version)
+	_, err = src.Next(version)
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+	version, err = src.Prev(version)
+	assert.NilError(t, err)
+	assert.Equal(t, uint(2), version)
+
+	r, identifier, err := src.ReadUp(2)
+	assert.NilError(t, err)
+	assert.Equal(t, "roles", identifier)
+	body, err := io.ReadAll(r)
+	assert.NilError(t, err)
+	assert.Equal(t, `INSERT INTO "roles" ("name", "role_id") VALUES ('admin', 1);`+"\n"+
+		`INSERT INTO "roles" ("name", "role_id") VALUES ('user', 2);`+"\n", string(body))
+
+	r, _, err = src.ReadDown(2)
+	assert.NilError(t, err)
+	body, err = io.ReadAll(r)
+	assert.NilError(t, err)
+	assert.Equal(t, `DELETE FROM "roles" WHERE "role_id" = 2;`+"\n"+
+		`DELETE FROM "roles" WHERE "role_id" = 1;`+"\n", string(body))
+
+	r, identifier, err = src.ReadUp(3)
+	assert.NilError(t, err)
+	assert.Equal(t, "add_role_level", identifier)
+	assert.NilError(t, r.Close())
+
+	_, _, err = src.ReadDown(3)
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+
+	assert.NilError(t, src.Close())
+}
+
+func TestSourceDuplicateVersion(t *testing.T) {
+	base, err := iofs.New(fstest.MapFS{
+		"1_create_roles.up.sql": {Data: []byte("CREATE TABLE roles (role_id int, name text);")},
+	}, ".")
+	assert.NilError(t, err)
+
+	src, err := New([]Step{{Version: 1, Migration: dbsql.NewMigration(debefix.NewData(),
+		postgres.QueryBuilderDialect{})}}, WithSource(base))
+	assert.NilError(t, err)
+
+	_, err = src.First()
+	assert.ErrorContains(t, err, "migration version 1 exists in both sources")
+}
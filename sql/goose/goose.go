@@ -0,0 +1,37 @@
+package goose
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// Up returns a goose migration function which executes the up statements of the migration.
+// Register it in the migration file using [goose.AddMigrationContext]:
+//
+//	func init() {
+//		goose.AddMigrationContext(debefixgoose.Up(migration), debefixgoose.Down(migration))
+//	}
+func Up(migration *dbsql.Migration) goose.GoMigrationContext {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		return migration.Up(ctx, tx)
+	}
+}
+
+// Down returns a goose migration function which deletes the rows inserted by the migration, in reverse order.
+func Down(migration *dbsql.Migration) goose.GoMigrationContext {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		return migration.Down(ctx, tx)
+	}
+}
+
+// NewGoMigration returns a goose migration with the version, to be used with [goose.WithGoMigrations] in a
+// [goose.Provider].
+func NewGoMigration(version int64, migration *dbsql.Migration) *goose.Migration {
+	return goose.NewGoMigration(version,
+		&goose.GoFunc{RunTx: Up(migration)},
+		&goose.GoFunc{RunTx: Down(migration)},
+	)
+}
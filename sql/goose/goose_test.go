@@ -0,0 +1,50 @@
+package goose
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	data := debefix.NewData()
+	data.AddValues(debefix.TableName("roles"),
+		debefix.MapValues{"role_id": 1, "name": "admin"},
+		debefix.MapValues{"role_id": 2, "name": "user"},
+	)
+	m := dbsql.NewMigration(data, postgres.QueryBuilderDialect{},
+		dbsql.WithMigrationKeyFields(debefix.TableName("roles"), "role_id"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "roles" ("name", "role_id") VALUES ($1, $2)`)).
+		WithArgs("admin", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "roles" ("name", "role_id") VALUES ($1, $2)`)).
+		WithArgs("user", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "roles" WHERE "role_id" = $1`)).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "roles" WHERE "role_id" = $1`)).
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.BeginTx(ctx, nil)
+	assert.NilError(t, err)
+	assert.NilError(t, Up(m)(ctx, tx))
+
+	tx, err = db.BeginTx(ctx, nil)
+	assert.NilError(t, err)
+	assert.NilError(t, Down(m)(ctx, tx))
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,16 @@
+package sql
+
+// QueryInterfaceMiddleware is a function that wraps a QueryInterface, to add behavior like logging, timing, retries
+// or query rewriting.
+type QueryInterfaceMiddleware func(next QueryInterface) QueryInterface
+
+// Chain returns a QueryInterfaceMiddleware which applies the middlewares in order, the first one being the
+// outermost.
+func Chain(middlewares ...QueryInterfaceMiddleware) QueryInterfaceMiddleware {
+	return func(next QueryInterface) QueryInterface {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
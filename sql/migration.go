@@ -0,0 +1,297 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Migration exposes a fixture as a migration step, for migration tools. The up statements insert and update the
+// fixture rows, and the down statements delete the inserted rows in reverse order.
+// The statements are built without accessing the database, so all key values must be set in the fixture, and
+// fields generated by the database (debefix.ResolveValue) are not supported. Updates are not reverted by the down
+// statements.
+// The down statements delete the rows using the key fields set by WithMigrationKeyFields, or the key fields of the
+// updates of the same table. It is an error if a table has no key fields.
+type Migration struct {
+	data      *debefix.Data
+	dialect   QueryBuilderDialect
+	keyFields map[string][]string // table ID: key field names
+}
+
+// MigrationStatement is a statement of a Migration.
+type MigrationStatement struct {
+	Query        string
+	Args         []any
+	RowsAffected int64 // if greater than 0, the number of rows the statement must affect.
+}
+
+// NewMigration creates a Migration for the data, using dialect to build the statements.
+func NewMigration(data *debefix.Data, dialect QueryBuilderDialect, options ...MigrationOption) *Migration {
+	ret := &Migration{
+		data:      data,
+		dialect:   dialect,
+		keyFields: map[string][]string{},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// MigrationOption is an option for NewMigration.
+type MigrationOption func(m *Migration)
+
+// WithMigrationKeyFields sets the fields used to delete the rows of a table. All the fields must be present in the
+// rows.
+func WithMigrationKeyFields(tableID debefix.TableID, fieldNames ...string) MigrationOption {
+	return func(m *Migration) {
+		m.keyFields[tableID.TableID()] = fieldNames
+	}
+}
+
+// Statements returns the up and down statements of the migration.
+func (m *Migration) Statements(ctx context.Context) (up []MigrationStatement, down []MigrationStatement, err error) {
+	return m.statements(ctx, m.dialect)
+}
+
+// Up executes the up statements.
+func (m *Migration) Up(ctx context.Context, db DB) error {
+	up, _, err := m.Statements(ctx)
+	if err != nil {
+		return err
+	}
+	return execMigrationStatements(ctx, db, up)
+}
+
+// Down executes the down statements.
+func (m *Migration) Down(ctx context.Context, db DB) error {
+	_, down, err := m.Statements(ctx)
+	if err != nil {
+		return err
+	}
+	return execMigrationStatements(ctx, db, down)
+}
+
+// SQL returns the up and down statements as SQL scripts, with the arguments formatted as literals, for migration
+// tools which read SQL files. Each statement is terminated by ";" on its own line.
+// Literals are formatted by the dialect if it implements QueryBuilderDialectLiteral, otherwise by
+// DefaultQuoteLiteral.
+func (m *Migration) SQL(ctx context.Context) (up string, down string, err error) {
+	upStmts, downStmts, err := m.statements(ctx, literalDialect{QueryBuilderDialect: m.dialect})
+	if err != nil {
+		return "", "", err
+	}
+	up, err = m.script(upStmts)
+	if err != nil {
+		return "", "", err
+	}
+	down, err = m.script(downStmts)
+	if err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+func (m *Migration) statements(ctx context.Context, dialect QueryBuilderDialect) ([]MigrationStatement,
+	[]MigrationStatement, error) {
+	type addedRow struct {
+		tableID debefix.TableID
+		fields  map[string]any
+	}
+	var up, down []MigrationStatement
+	var added []addedRow
+	updateKeyFields := map[string][]string{}
+
+	_, err := debefix.Resolve(ctx, m.data, db.ResolveFunc(func(ctx context.Context, resolveInfo db.ResolveDBInfo,
+		fields map[string]any, returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+		for fn := range returnFields {
+			return nil, fmt.Errorf("field '%s' of table '%s' is generated by the database, which is not supported in migrations",
+				fn, resolveInfo.TableID.TableID())
+		}
+
+		query, args, err := BuildQuery(dialect, resolveInfo, fields, nil)
+		if err != nil {
+			return nil, err
+		}
+		up = append(up, MigrationStatement{Query: query, Args: args})
+
+		switch resolveInfo.Type {
+		case debefix.ResolveTypeAdd:
+			added = append(added, addedRow{tableID: resolveInfo.TableID, fields: fields})
+		case debefix.ResolveTypeUpdate:
+			if _, ok := updateKeyFields[resolveInfo.TableID.TableID()]; !ok {
+				updateKeyFields[resolveInfo.TableID.TableID()] = resolveInfo.UpdateKeyFields
+			}
+		}
+		return nil, nil
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range slices.Backward(added) {
+		keyFieldNames, ok := m.keyFields[row.tableID.TableID()]
+		if !ok {
+			keyFieldNames = updateKeyFields[row.tableID.TableID()]
+		}
+		keyFields, err := rowKeyFields(row.tableID, keyFieldNames, row.fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		query, args, err := BuildDeleteQuery(dialect, row.tableID, keyFields)
+		if err != nil {
+			return nil, nil, err
+		}
+		down = append(down, MigrationStatement{Query: query, Args: args, RowsAffected: 1})
+	}
+
+	return up, down, nil
+}
+
+// rowKeyFields returns the key fields of a row.
+func rowKeyFields(tableID debefix.TableID, keyFieldNames []string, fields map[string]any) (map[string]any, error) {
+	if len(keyFieldNames) == 0 {
+		return nil, fmt.Errorf("no key fields to delete rows of table '%s', set them using WithMigrationKeyFields",
+			tableID.TableID())
+	}
+	ret := map[string]any{}
+	for _, fn := range keyFieldNames {
+		fv, ok := fields[fn]
+		if !ok {
+			return nil, fmt.Errorf("key field '%s' not set in row of table '%s'", fn, tableID.TableID())
+		}
+		ret[fn] = fv
+	}
+	return ret, nil
+}
+
+var literalPlaceholderRe = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// script formats the statements built with literalDialect as a SQL script.
+func (m *Migration) script(stmts []MigrationStatement) (string, error) {
+	quoteLiteral := DefaultQuoteLiteral
+	if ld, ok := m.dialect.(QueryBuilderDialectLiteral); ok {
+		quoteLiteral = ld.QuoteLiteral
+	}
+
+	var b strings.Builder
+	for _, stmt := range stmts {
+		var lerr error
+		query := literalPlaceholderRe.ReplaceAllStringFunc(stmt.Query, func(s string) string {
+			idx, _ := strconv.Atoi(strings.Trim(s, "\x00"))
+			if idx >= len(stmt.Args) {
+				lerr = fmt.Errorf("missing argument %d for query `%s`", idx, stmt.Query)
+				return s
+			}
+			literal, err := quoteLiteral(stmt.Args[idx])
+			if err != nil {
+				lerr = err
+			}
+			return literal
+		})
+		if lerr != nil {
+			return "", lerr
+		}
+		b.WriteString(query)
+		b.WriteString(";\n")
+	}
+	return b.String(), nil
+}
+
+func execMigrationStatements(ctx context.Context, db DB, stmts []MigrationStatement) error {
+	for _, stmt := range stmts {
+		result, err := db.ExecContext(ctx, stmt.Query, stmt.Args...)
+		if err != nil {
+			return fmt.Errorf("error executing `%s`: %w", stmt.Query, err)
+		}
+		if stmt.RowsAffected > 0 {
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("error getting affected rows of `%s`: %w", stmt.Query, err)
+			}
+			if affected != stmt.RowsAffected {
+				return fmt.Errorf("expected `%s` with args %v to affect %d rows, but it affected %d", stmt.Query,
+					stmt.Args, stmt.RowsAffected, affected)
+			}
+		}
+	}
+	return nil
+}
+
+// QueryBuilderDialectLiteral is an optional interface for QueryBuilderDialect, which formats values as SQL
+// literals.
+type QueryBuilderDialectLiteral interface {
+	QuoteLiteral(value any) (string, error)
+}
+
+// DefaultQuoteLiteral formats a value as a standard SQL literal. [driver.Valuer] values are converted first,
+// times are formatted as strings with nanoseconds and timezone, and [fmt.Stringer] values are formatted as
+// strings.
+func DefaultQuoteLiteral(value any) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return quoteStringLiteral(v), nil
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		return quoteStringLiteral(v.Format("2006-01-02 15:04:05.999999999Z07:00")), nil
+	case fmt.Stringer:
+		return quoteStringLiteral(v.String()), nil
+	default:
+		return "", fmt.Errorf("unsupported literal type %T", value)
+	}
+}
+
+func quoteStringLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// literalDialect generates placeholders which are replaced by literals by Migration.SQL.
+type literalDialect struct {
+	QueryBuilderDialect
+}
+
+func (d literalDialect) NewPlaceholderProvider() QueryBuilderPlaceholderProvider {
+	return &literalPlaceholderProvider{}
+}
+
+type literalPlaceholderProvider struct {
+	c int
+}
+
+func (p *literalPlaceholderProvider) Next() (placeholder string, argName string) {
+	placeholder = fmt.Sprintf("\x00%d\x00", p.c)
+	p.c++
+	return placeholder, ""
+}
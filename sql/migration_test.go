@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestMigration(t *testing.T) {
+	ctx := context.Background()
+
+	data := debefix.NewData()
+	tagIID := data.AddWithID(tableTags,
+		debefix.MapValues{
+			"tag_id": 1,
+			"name":   "Go's",
+		})
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id":    10,
+			"title":      "First post",
+			"tag_id":     tagIID.ValueForField("tag_id"),
+			"created_at": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	)
+	data.Update(tagIID.UpdateQuery([]string{"tag_id"}), debefix.UpdateActionSetValues{
+		Values: debefix.MapValues{"name": "Golang"},
+	})
+
+	m := NewMigration(data, DefaultQueryBuilderDialect{}, WithMigrationKeyFields(tablePosts, "post_id"))
+
+	up, down, err := m.Statements(ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []MigrationStatement{
+		{Query: "INSERT INTO public.tags (name, tag_id) VALUES (?, ?)", Args: []any{"Go's", 1}},
+		{Query: "INSERT INTO public.posts (created_at, post_id, tag_id, title) VALUES (?, ?, ?, ?)",
+			Args: []any{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 10, 1, "First post"}},
+		{Query: "UPDATE public.tags SET name = ? WHERE tag_id = ?", Args: []any{"Golang", 1}},
+	}, up)
+	assert.DeepEqual(t, []MigrationStatement{
+		{Query: "DELETE FROM public.posts WHERE post_id = ?", Args: []any{10}, RowsAffected: 1},
+		// the key fields of the tags update are used.
+		{Query: "DELETE FROM public.tags WHERE tag_id = ?", Args: []any{1}, RowsAffected: 1},
+	}, down)
+
+	upSQL, downSQL, err := m.SQL(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, "INSERT INTO public.tags (name, tag_id) VALUES ('Go''s', 1);\n"+
+		"INSERT INTO public.posts (created_at, post_id, tag_id, title) VALUES ('2024-01-02 03:04:05Z', 10, 1, 'First post');\n"+
+		"UPDATE public.tags SET name = 'Golang' WHERE tag_id = 1;\n", upSQL)
+	assert.Equal(t, "DELETE FROM public.posts WHERE post_id = 10;\n"+
+		"DELETE FROM public.tags WHERE tag_id = 1;\n", downSQL)
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM public.posts WHERE post_id = ?")).WithArgs(10).
+		WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM public.tags WHERE tag_id = ?")).WithArgs(1).
+		WillReturnResult(dbmock.NewResult(0, 0))
+
+	err = m.Down(ctx, sqldb)
+	assert.Error(t, err, "expected `DELETE FROM public.tags WHERE tag_id = ?` with args [1] to affect 1 rows, "+
+		"but it affected 0")
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationKeyFields(t *testing.T) {
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{"tag_id": 1, "name": "Go"},
+	)
+
+	_, _, err := NewMigration(data, DefaultQueryBuilderDialect{}).Statements(context.Background())
+	assert.ErrorContains(t, err, "no key fields to delete rows of table 'public.tags'")
+
+	_, _, err = NewMigration(data, DefaultQueryBuilderDialect{}, WithMigrationKeyFields(tableTags, "id")).
+		Statements(context.Background())
+	assert.ErrorContains(t, err, "key field 'id' not set in row of table 'public.tags'")
+}
+
+func TestMigrationGeneratedField(t *testing.T) {
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"name":   "Go",
+		})
+
+	_, _, err := NewMigration(data, DefaultQueryBuilderDialect{}).Statements(context.Background())
+	assert.ErrorContains(t, err, "field 'tag_id' of table 'public.tags' is generated by the database")
+}
@@ -0,0 +1,43 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+type QueryBuilderDialect struct {
+}
+
+func (d QueryBuilderDialect) QuoteTable(tableName string) string {
+	return quoteIdentifier(tableName)
+}
+
+func (d QueryBuilderDialect) QuoteField(fieldName string) string {
+	return quoteIdentifier(fieldName)
+}
+
+func (d QueryBuilderDialect) NewPlaceholderProvider() sql.QueryBuilderPlaceholderProvider {
+	return sql.DefaultQueryBuilderDialect{}.NewPlaceholderProvider()
+}
+
+// QuoteLiteral formats a value as a mysql literal. Backslashes in strings are escaped, and times are formatted in
+// UTC without timezone, like the go-sql-driver/mysql driver does by default.
+func (d QueryBuilderDialect) QuoteLiteral(value any) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(v) + "'", nil
+	case time.Time:
+		return "'" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'", nil
+	}
+	return sql.DefaultQuoteLiteral(value)
+}
@@ -0,0 +1,10 @@
+package mysql
+
+import (
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// QueryBuilder returns a mysql-compatible sql.QueryBuilder
+func QueryBuilder() sql.QueryBuilder {
+	return sql.NewQueryBuilder(QueryBuilderDialect{})
+}
@@ -0,0 +1,53 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags = debefix.TableName("public.tags")
+)
+
+func TestResolveGenerated(t *testing.T) {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("all"),
+			"tag_name": "All",
+		},
+	)
+
+	type sqlQuery struct {
+		SQL  string
+		Args []any
+	}
+
+	expectedQueryList := []sqlQuery{
+		{
+			SQL:  "INSERT INTO `public.tags` (`tag_name`) VALUES (?) RETURNING `tag_id`",
+			Args: []any{"All"},
+		},
+	}
+
+	ctx := context.Background()
+
+	var queryList []sqlQuery
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(
+		sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+			queryList = append(queryList, sqlQuery{
+				SQL:  query,
+				Args: args,
+			})
+			return map[string]any{"tag_id": 1}, nil
+		})))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, expectedQueryList, queryList)
+}
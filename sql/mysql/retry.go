@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+var errorNumberRe = regexp.MustCompile(`^Error (\d+)`)
+
+// RetryClassifier is a sql.RetryClassifier for mysql errors. It uses the error number from the error message, in
+// the "Error 1213 (40001): ..." format used by github.com/go-sql-driver/mysql.
+// Deadlocks (1213) and lock wait timeouts (1205) are transient errors, and server shutdown and connection errors
+// (1053, 2002, 2003, 2006, 2013) are connection errors.
+func RetryClassifier(err error) sql.RetryErrorKind {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		m := errorNumberRe.FindStringSubmatch(e.Error())
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case "1213", "1205":
+			return sql.RetryErrorTransient
+		case "1053", "2002", "2003", "2006", "2013":
+			return sql.RetryErrorConnection
+		}
+		break
+	}
+	return sql.DefaultRetryClassifier(err)
+}
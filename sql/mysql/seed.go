@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// SeedLocker returns a sql.SeedLocker which uses a mysql named lock (GET_LOCK) for each seed name.
+// As the lock is held by the session, if db is a connection pool, like [database/sql.DB], a dedicated connection is
+// used while the lock is held.
+func SeedLocker(db sql.DB) sql.SeedLocker {
+	return sql.SeedLockerFunc(func(ctx context.Context, name string) (func(ctx context.Context) error, error) {
+		session, release, err := sql.SessionDB(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		lockName := seedLockName(name)
+		if err := getLock(ctx, session, lockName); err != nil {
+			return nil, errors.Join(err, release())
+		}
+		return func(ctx context.Context) error {
+			_, err := session.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+			return errors.Join(err, release())
+		}, nil
+	})
+}
+
+// SeedLocker returns the SeedLocker function of the package.
+func (d QueryBuilderDialect) SeedLocker(db sql.DB) sql.SeedLocker {
+	return SeedLocker(db)
+}
+
+func getLock(ctx context.Context, db sql.DB, lockName string) error {
+	rows, err := db.QueryContext(ctx, "SELECT GET_LOCK(?, -1)", lockName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var locked *int64
+	if rows.Next() {
+		if err := rows.Scan(&locked); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if locked == nil || *locked != 1 {
+		return fmt.Errorf("could not get lock '%s'", lockName)
+	}
+	return nil
+}
+
+// seedLockName returns the lock name of a seed name. It is hashed as lock names are limited to 64 characters.
+func seedLockName(name string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return fmt.Sprintf("debefix_seed_%x", h.Sum64())
+}
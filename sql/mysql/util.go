@@ -0,0 +1,7 @@
+package mysql
+
+import "strings"
+
+func quoteIdentifier(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
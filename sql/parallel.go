@@ -0,0 +1,25 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+
+	"github.com/rrgmc/debefix-db/v2"
+)
+
+// ConnProvider returns a db.ResolveDBCallbackProvider for db.ResolveParallel, which executes each group of tables
+// on a separate connection of the pool.
+func ConnProvider(pool *sql.DB, queryBuilder QueryBuilder, options ...SQLQueryInterfaceOption) db.ResolveDBCallbackProvider {
+	return func(ctx context.Context) (db.ResolveDBCallback, func() error, error) {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		qi := NewSQLQueryInterface(conn, options...)
+		return ResolveDBFunc(qi, queryBuilder), func() error {
+			return errors.Join(qi.(io.Closer).Close(), conn.Close())
+		}, nil
+	}
+}
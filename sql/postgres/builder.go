@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"encoding/hex"
 	"fmt"
 
 	"github.com/rrgmc/debefix-db/v2/sql"
@@ -32,3 +33,11 @@ func (p *QueryBuilderDialectPlaceholderProvider) Next() (placeholder string, arg
 	p.c++
 	return fmt.Sprintf("$%d", p.c), ""
 }
+
+// QuoteLiteral formats a value as a postgres literal. Byte slices are formatted as bytea literals.
+func (d QueryBuilderDialect) QuoteLiteral(value any) (string, error) {
+	if b, ok := value.([]byte); ok {
+		return `'\x` + hex.EncodeToString(b) + `'::bytea`, nil
+	}
+	return sql.DefaultQuoteLiteral(value)
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/extract"
+)
+
+// NewExtractor creates an extract.Extractor for a postgres database.
+// If no schemas are passed, the "public" schema is used. Table names are qualified by the schema, like
+// "public.tags".
+func NewExtractor(db sql.DB, schemas []string, options ...extract.Option) *extract.Extractor {
+	return extract.NewExtractor(db, QueryBuilderDialect{}, NewExtractMetadataProvider(db, schemas...), options...)
+}
+
+// NewExtractMetadataProvider returns an extract.MetadataProvider which reads the metadata from the postgres catalog.
+// If no schemas are passed, the "public" schema is used.
+func NewExtractMetadataProvider(db sql.DB, schemas ...string) extract.MetadataProvider {
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	return &extractMetadataProvider{db: db, schemas: schemas}
+}
+
+type extractMetadataProvider struct {
+	db      sql.DB
+	schemas []string
+}
+
+func (p *extractMetadataProvider) Metadata(ctx context.Context) (*extract.Metadata, error) {
+	ret := &extract.Metadata{
+		Tables: map[string]*extract.TableInfo{},
+	}
+
+	err := p.query(ctx, `SELECT n.nspname, c.relname
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p') AND n.nspname = ANY($1)`, func(values []string) {
+		name := values[0] + "." + values[1]
+		ret.Tables[name] = &extract.TableInfo{Name: name}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading tables: %w", err)
+	}
+
+	err = p.query(ctx, `SELECT n.nspname, c.relname, a.attname
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = ANY($1) AND a.attnum > 0 AND NOT a.attisdropped
+  AND (a.attidentity <> '' OR a.attgenerated <> '' OR pg_get_expr(d.adbin, d.adrelid) LIKE 'nextval(%')
+ORDER BY n.nspname, c.relname, a.attnum`, func(values []string) {
+		if table, ok := ret.Tables[values[0]+"."+values[1]]; ok {
+			table.GeneratedColumns = append(table.GeneratedColumns, values[2])
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading generated columns: %w", err)
+	}
+
+	err = p.query(ctx, `SELECT n.nspname, c.relname, a.attname
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+CROSS JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+WHERE con.contype = 'p' AND n.nspname = ANY($1)
+ORDER BY n.nspname, c.relname, k.ord`, func(values []string) {
+		if table, ok := ret.Tables[values[0]+"."+values[1]]; ok {
+			table.PrimaryKey = append(table.PrimaryKey, values[2])
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading primary keys: %w", err)
+	}
+
+	err = p.query(ctx, `SELECT n.nspname, c.relname, con.conname, a.attname, fn.nspname, fc.relname, fa.attname
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_class fc ON fc.oid = con.confrelid
+JOIN pg_namespace fn ON fn.oid = fc.relnamespace
+CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+JOIN pg_attribute fa ON fa.attrelid = con.confrelid AND fa.attnum = k.fattnum
+WHERE con.contype = 'f' AND n.nspname = ANY($1)
+ORDER BY n.nspname, c.relname, con.conname, k.ord`, func(values []string) {
+		table, ok := ret.Tables[values[0]+"."+values[1]]
+		if !ok {
+			return
+		}
+		if len(table.ForeignKeys) == 0 || table.ForeignKeys[len(table.ForeignKeys)-1].Name != values[2] {
+			table.ForeignKeys = append(table.ForeignKeys, extract.ForeignKey{
+				Name:     values[2],
+				RefTable: values[4] + "." + values[5],
+			})
+		}
+		fk := &table.ForeignKeys[len(table.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, values[3])
+		fk.RefColumns = append(fk.RefColumns, values[6])
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading foreign keys: %w", err)
+	}
+
+	return ret, nil
+}
+
+// query executes a catalog query with the schemas as the first parameter, calling the callback with the string
+// values of each row.
+func (p *extractMetadataProvider) query(ctx context.Context, query string, cb func(values []string)) error {
+	rows, err := p.db.QueryContext(ctx, query, arrayLiteral(p.schemas))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		values := make([]string, len(cols))
+		pointers := make([]any, len(cols))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		cb(values)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix-db/v2/sql/extract"
+	"gotest.tools/v3/assert"
+)
+
+func TestExtractor(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT n.nspname, c.relname\s+FROM pg_class`).WithArgs(`{"public"}`).
+		WillReturnRows(dbmock.NewRows("nspname", "relname").AddRow("public", "tags"))
+	mock.ExpectQuery(`attgenerated`).WithArgs(`{"public"}`).
+		WillReturnRows(dbmock.NewRows("nspname", "relname", "attname").AddRow("public", "tags", "tag_id"))
+	mock.ExpectQuery(`con.contype = 'p'`).WithArgs(`{"public"}`).
+		WillReturnRows(dbmock.NewRows("nspname", "relname", "attname").AddRow("public", "tags", "tag_id"))
+	mock.ExpectQuery(`con.contype = 'f'`).WithArgs(`{"public"}`).
+		WillReturnRows(dbmock.NewRows("nspname", "relname", "conname", "attname", "nspname", "relname", "attname"))
+	// the schema and table names are quoted separately.
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "public"."tags" WHERE tag_id = $1`)).
+		WithArgs(1).
+		WillReturnRows(dbmock.NewRows("tag_id", "name").AddRow(int64(1), "Go"))
+
+	result, err := NewExtractor(db, nil).Extract(ctx,
+		extract.Root{Table: "public.tags", Where: "tag_id = $1", Args: []any{1}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"public.tags"}, result.TableNames())
+	assert.Equal(t, 1, len(result.Tables["public.tags"].Rows))
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
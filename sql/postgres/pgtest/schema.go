@@ -0,0 +1,299 @@
+package pgtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Schema is a uniquely named postgres schema created for a test, which allows tests using the same database to
+// run in parallel.
+type Schema struct {
+	// Name is the schema name.
+	Name string
+	// Conn is a connection with the search_path set to the schema.
+	Conn *sql.Conn
+
+	rewriteSchemas []string
+}
+
+// NewSchema creates a uniquely named schema, with the tables of the template schema set by WithTemplateSchema, or
+// created by the statements set by WithDDL. The schema is dropped when the test finishes.
+func NewSchema(t testing.TB, db *sql.DB, options ...SchemaOption) *Schema {
+	t.Helper()
+
+	optns := schemaOptions{
+		prefix: "test",
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+	if len(optns.rewriteSchemas) == 0 {
+		if optns.templateSchema != "" {
+			optns.rewriteSchemas = []string{optns.templateSchema}
+		} else {
+			optns.rewriteSchemas = []string{"public"}
+		}
+	}
+
+	ctx := context.Background()
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatalf("error generating schema name: %s", err)
+	}
+	ret := &Schema{
+		Name:           optns.prefix + "_" + hex.EncodeToString(suffix),
+		rewriteSchemas: optns.rewriteSchemas,
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("error getting connection: %s", err)
+	}
+	ret.Conn = conn
+
+	t.Cleanup(func() {
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", quoteIdentifier(ret.Name)))
+		if err != nil {
+			t.Errorf("error dropping schema '%s': %s", ret.Name, err)
+		}
+		_ = conn.Close()
+	})
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA %s", quoteIdentifier(ret.Name))); err != nil {
+		t.Fatalf("error creating schema '%s': %s", ret.Name, err)
+	}
+
+	if optns.templateSchema != "" {
+		if err := ret.cloneSchema(ctx, optns.templateSchema); err != nil {
+			t.Fatalf("error cloning schema '%s': %s", optns.templateSchema, err)
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s", quoteIdentifier(ret.Name))); err != nil {
+		t.Fatalf("error setting search_path: %s", err)
+	}
+
+	for _, ddl := range optns.ddl {
+		if _, err := conn.ExecContext(ctx, ddl); err != nil {
+			t.Fatalf("error executing DDL: %s", err)
+		}
+	}
+
+	return ret
+}
+
+// SchemaOption is an option for NewSchema.
+type SchemaOption func(*schemaOptions)
+
+// WithTemplateSchema clones the tables of the template schema, including indexes, defaults, constraints and
+// foreign keys between them.
+// The sequences of columns using "serial" types are also cloned, so they don't share the sequence of the template
+// table.
+func WithTemplateSchema(templateSchema string) SchemaOption {
+	return func(o *schemaOptions) {
+		o.templateSchema = templateSchema
+	}
+}
+
+// WithDDL sets statements to be executed after the schema is created, with the search_path set to it.
+func WithDDL(ddl ...string) SchemaOption {
+	return func(o *schemaOptions) {
+		o.ddl = append(o.ddl, ddl...)
+	}
+}
+
+// WithPrefix sets the prefix of the schema name. The default is "test".
+func WithPrefix(prefix string) SchemaOption {
+	return func(o *schemaOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithRewriteSchemas sets the schemas of the table IDs which are replaced by the test schema in Dialect. The
+// default is the template schema if set, otherwise "public".
+func WithRewriteSchemas(schemas ...string) SchemaOption {
+	return func(o *schemaOptions) {
+		o.rewriteSchemas = append(o.rewriteSchemas, schemas...)
+	}
+}
+
+type schemaOptions struct {
+	prefix         string
+	templateSchema string
+	ddl            []string
+	rewriteSchemas []string
+}
+
+// Dialect returns a postgres sql.QueryBuilderDialect which replaces the schema prefixes of table IDs by the test
+// schema. Table IDs without schemas are also set to the test schema.
+func (s *Schema) Dialect() dbsql.QueryBuilderDialect {
+	return &SchemaDialect{
+		QueryBuilderDialect: postgres.QueryBuilderDialect{},
+		Schema:              s.Name,
+		RewriteSchemas:      s.rewriteSchemas,
+	}
+}
+
+// QueryInterface returns a sql.QueryInterface which executes the queries in the schema connection.
+func (s *Schema) QueryInterface() dbsql.QueryInterface {
+	return dbsql.NewSQLQueryInterface(s.Conn)
+}
+
+// ResolveDBFunc returns a db.ResolveDBCallback which stores the data in the test schema.
+func (s *Schema) ResolveDBFunc() db.ResolveDBCallback {
+	return dbsql.ResolveDBFunc(s.QueryInterface(), dbsql.NewQueryBuilder(s.Dialect()))
+}
+
+// ResolveFunc returns a debefix.ResolveCallback which stores the data in the test schema.
+func (s *Schema) ResolveFunc() debefix.ResolveCallback {
+	return db.ResolveFunc(s.ResolveDBFunc())
+}
+
+// cloneSchema creates the tables of the template schema in the test schema.
+func (s *Schema) cloneSchema(ctx context.Context, templateSchema string) error {
+	tables, err := queryStrings(ctx, s.Conn, `SELECT c.relname FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+		ORDER BY c.relname`, templateSchema)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		_, err := s.Conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s.%s (LIKE %s.%s INCLUDING ALL)",
+			quoteIdentifier(s.Name), quoteIdentifier(table), quoteIdentifier(templateSchema), quoteIdentifier(table)))
+		if err != nil {
+			return fmt.Errorf("error creating table '%s': %w", table, err)
+		}
+	}
+
+	// serial column defaults copied by LIKE use the template sequences, so create sequences for them in the test
+	// schema. Identity columns are copied with their own sequences.
+	rows, err := s.Conn.QueryContext(ctx, `SELECT t.relname, a.attname, seq.relname
+		FROM pg_catalog.pg_class seq
+		JOIN pg_catalog.pg_depend d ON d.objid = seq.oid AND d.classid = 'pg_catalog.pg_class'::regclass
+			AND d.refclassid = 'pg_catalog.pg_class'::regclass AND d.deptype = 'a'
+		JOIN pg_catalog.pg_class t ON t.oid = d.refobjid
+		JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+		WHERE seq.relkind = 'S' AND n.nspname = $1
+		ORDER BY t.relname, a.attname`, templateSchema)
+	if err != nil {
+		return err
+	}
+	var seqs [][3]string
+	for rows.Next() {
+		var seq [3]string
+		if err := rows.Scan(&seq[0], &seq[1], &seq[2]); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		seqs = append(seqs, seq)
+	}
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		table := quoteIdentifier(s.Name) + "." + quoteIdentifier(seq[0])
+		seqName := quoteIdentifier(s.Name) + "." + quoteIdentifier(seq[2])
+		if _, err := s.Conn.ExecContext(ctx, fmt.Sprintf("CREATE SEQUENCE %s OWNED BY %s.%s", seqName, table,
+			quoteIdentifier(seq[1]))); err != nil {
+			return fmt.Errorf("error creating sequence '%s': %w", seq[2], err)
+		}
+		if _, err := s.Conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT nextval('%s')",
+			table, quoteIdentifier(seq[1]), strings.ReplaceAll(seqName, "'", "''"))); err != nil {
+			return fmt.Errorf("error setting default of column '%s' of table '%s': %w", seq[1], seq[0], err)
+		}
+	}
+
+	// foreign keys are not copied by LIKE. With the search_path set to the template schema, the references to its
+	// tables are returned unqualified, so they reference the test schema tables when created with the search_path
+	// set to it.
+	if _, err := s.Conn.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s",
+		quoteIdentifier(templateSchema))); err != nil {
+		return err
+	}
+	rows, err = s.Conn.QueryContext(ctx, `SELECT c.relname, con.conname, pg_catalog.pg_get_constraintdef(con.oid)
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND con.contype = 'f'
+		ORDER BY c.relname, con.conname`, templateSchema)
+	if err != nil {
+		return err
+	}
+	var fks [][3]string
+	for rows.Next() {
+		var fk [3]string
+		if err := rows.Scan(&fk[0], &fk[1], &fk[2]); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		fks = append(fks, fk)
+	}
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return err
+	}
+
+	if _, err := s.Conn.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s", quoteIdentifier(s.Name))); err != nil {
+		return err
+	}
+	for _, fk := range fks {
+		_, err := s.Conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+			quoteIdentifier(fk[0]), quoteIdentifier(fk[1]), fk[2]))
+		if err != nil {
+			return fmt.Errorf("error creating foreign key '%s': %w", fk[1], err)
+		}
+	}
+	return nil
+}
+
+// SchemaDialect is a sql.QueryBuilderDialect which replaces the schema prefix of table IDs.
+type SchemaDialect struct {
+	dbsql.QueryBuilderDialect
+	Schema         string   // the schema which replaces the table IDs schemas.
+	RewriteSchemas []string // the schemas to replace. Table IDs with other schemas are passed unchanged to the dialect.
+}
+
+func (d *SchemaDialect) QuoteTable(tableName string) string {
+	schema, table, ok := strings.Cut(tableName, ".")
+	if !ok {
+		table = tableName
+	} else if !slices.Contains(d.RewriteSchemas, schema) {
+		return d.QueryBuilderDialect.QuoteTable(tableName)
+	}
+	return d.QueryBuilderDialect.QuoteTable(d.Schema) + "." + d.QueryBuilderDialect.QuoteTable(table)
+}
+
+func queryStrings(ctx context.Context, conn *sql.Conn, query string, args ...any) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		ret = append(ret, s)
+	}
+	return ret, rows.Err()
+}
+
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
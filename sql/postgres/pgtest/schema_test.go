@@ -0,0 +1,78 @@
+package pgtest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestSchemaDialect(t *testing.T) {
+	d := &SchemaDialect{
+		QueryBuilderDialect: postgres.QueryBuilderDialect{},
+		Schema:              "test_1",
+		RewriteSchemas:      []string{"public"},
+	}
+	assert.Equal(t, `"test_1"."tags"`, d.QuoteTable("public.tags"))
+	assert.Equal(t, `"test_1"."tags"`, d.QuoteTable("tags"))
+	// not rewritten, passed unchanged to the dialect.
+	assert.Equal(t, postgres.QueryBuilderDialect{}.QuoteTable("audit.events"), d.QuoteTable("audit.events"))
+	assert.Equal(t, `"tag_id"`, d.QuoteField("tag_id"))
+}
+
+func TestNewSchema(t *testing.T) {
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	schemaRe := `"test_[0-9a-f]{16}"`
+
+	mock.ExpectExec(`CREATE SCHEMA ` + schemaRe).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT c.relname FROM pg_catalog.pg_class`).WithArgs("template").
+		WillReturnRows(dbmock.NewRows("relname").AddRow("posts").AddRow("tags"))
+	mock.ExpectExec(`CREATE TABLE ` + schemaRe + `\."posts" \(LIKE "template"\."posts" INCLUDING ALL\)`).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE ` + schemaRe + `\."tags" \(LIKE "template"\."tags" INCLUDING ALL\)`).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectQuery(`pg_depend`).WithArgs("template").
+		WillReturnRows(dbmock.NewRows("relname", "attname", "relname").
+			AddRow("tags", "tag_id", "tags_tag_id_seq"))
+	mock.ExpectExec(`CREATE SEQUENCE ` + schemaRe + `\."tags_tag_id_seq" OWNED BY ` + schemaRe + `\."tags"\."tag_id"`).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE ` + schemaRe + `\."tags" ALTER COLUMN "tag_id" SET DEFAULT nextval\('` + schemaRe +
+		`\."tags_tag_id_seq"'\)`).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`SET search_path TO "template"`).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectQuery(`pg_get_constraintdef`).WithArgs("template").
+		WillReturnRows(dbmock.NewRows("relname", "conname", "def").
+			AddRow("posts", "posts_tag_id_fkey", "FOREIGN KEY (tag_id) REFERENCES tags(tag_id)"))
+	mock.ExpectExec(`SET search_path TO ` + schemaRe).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE "posts" ADD CONSTRAINT "posts_tag_id_fkey" FOREIGN KEY (tag_id) REFERENCES tags(tag_id)`)).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`SET search_path TO ` + schemaRe).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX tags_name`).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO `+schemaRe+regexp.QuoteMeta(`."tags" ("name", "tag_id") VALUES ($1, $2)`)).
+		WithArgs("Go", 1).WillReturnResult(dbmock.NewResult(0, 1))
+
+	var schema *Schema
+	t.Run("test", func(t *testing.T) {
+		schema = NewSchema(t, db, WithTemplateSchema("template"),
+			WithDDL("CREATE INDEX tags_name ON tags (name)"))
+		assert.Assert(t, strings.HasPrefix(schema.Name, "test_"))
+
+		data := debefix.NewData()
+		data.AddValues(debefix.TableName("template.tags"),
+			debefix.MapValues{"tag_id": 1, "name": "Go"},
+		)
+		_, err := debefix.Resolve(context.Background(), data, schema.ResolveFunc())
+		assert.NilError(t, err)
+
+		mock.ExpectExec(`DROP SCHEMA IF EXISTS ` + schemaRe + ` CASCADE`).WillReturnResult(dbmock.NewResult(0, 0))
+	})
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
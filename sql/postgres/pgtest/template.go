@@ -0,0 +1,262 @@
+package pgtest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Template is a postgres template database with the data resolved, from which a copy can be created for each
+// test using NewDatabase.
+// The template is only rebuilt when the hash of the data and schema changes. The hash is stored as the template
+// database comment.
+type Template struct {
+	admin      *sql.DB
+	driverName string
+	dsn        func(database string) string
+	name       string
+	data       *debefix.Data
+	options    templateOptions
+
+	prepareOnce sync.Once
+	prepareErr  error
+}
+
+// NewTemplate creates a Template named name. admin must be connected to a database other than the template, like
+// "postgres", with a user allowed to create databases. driverName and dsn are used to open connections to the
+// created databases.
+func NewTemplate(admin *sql.DB, driverName string, dsn func(database string) string, name string,
+	data *debefix.Data, options ...TemplateOption) *Template {
+	ret := &Template{
+		admin:      admin,
+		driverName: driverName,
+		dsn:        dsn,
+		name:       name,
+		data:       data,
+	}
+	for _, opt := range options {
+		opt(&ret.options)
+	}
+	return ret
+}
+
+// TemplateOption is an option for NewTemplate.
+type TemplateOption func(*templateOptions)
+
+// WithTemplateDDL sets statements which create the schema of the template database. They are part of the hash.
+func WithTemplateDDL(ddl ...string) TemplateOption {
+	return func(o *templateOptions) {
+		o.ddl = append(o.ddl, ddl...)
+	}
+}
+
+// WithTemplateSchemaFunc sets a function which creates the schema of the template database, like running
+// migrations. As the function can't be hashed, version must change when the schema changes, like the last
+// migration version.
+func WithTemplateSchemaFunc(version string, schemaFunc func(ctx context.Context, db *sql.DB) error) TemplateOption {
+	return func(o *templateOptions) {
+		o.schemaVersion = version
+		o.schemaFunc = schemaFunc
+	}
+}
+
+// WithTemplateDataVersion sets a version which is part of the hash. Functions in the data, like debefix.ValueFunc,
+// are hashed by their type only, so version must change when their behavior changes.
+func WithTemplateDataVersion(version string) TemplateOption {
+	return func(o *templateOptions) {
+		o.dataVersion = version
+	}
+}
+
+// WithTemplateResolveCallback sets the resolve callback used to insert the data in the template database. The
+// default is postgres.ResolveFunc using a dbsql.NewSQLQueryInterface of db.
+func WithTemplateResolveCallback(resolveCallback dbsql.SeedResolveCallback) TemplateOption {
+	return func(o *templateOptions) {
+		o.resolveCallback = resolveCallback
+	}
+}
+
+type templateOptions struct {
+	ddl             []string
+	schemaVersion   string
+	schemaFunc      func(ctx context.Context, db *sql.DB) error
+	dataVersion     string
+	resolveCallback dbsql.SeedResolveCallback
+}
+
+// Hash returns the hash of the data and schema.
+func (t *Template) Hash() (string, error) {
+	dataHash, err := db.DataHash(t.data)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "data %s\n", dataHash)
+	for _, ddl := range t.options.ddl {
+		_, _ = fmt.Fprintf(h, "ddl %q\n", ddl)
+	}
+	_, _ = fmt.Fprintf(h, "schema version %q\n", t.options.schemaVersion)
+	_, _ = fmt.Fprintf(h, "data version %q\n", t.options.dataVersion)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Prepare builds the template database, if it doesn't exist or its hash changed. It is called by NewDatabase.
+// An advisory lock is held while building, so concurrent test processes don't build it at the same time.
+func (t *Template) Prepare(ctx context.Context) error {
+	t.prepareOnce.Do(func() {
+		t.prepareErr = t.prepare(ctx)
+	})
+	return t.prepareErr
+}
+
+// NewDatabase creates a database copied from the template, and returns a connection to it and its DSN. The
+// database is dropped when the test finishes.
+func (t *Template) NewDatabase(tb testing.TB) (*sql.DB, string) {
+	tb.Helper()
+	ctx := context.Background()
+
+	if err := t.Prepare(ctx); err != nil {
+		tb.Fatalf("error preparing template database '%s': %s", t.name, err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		tb.Fatalf("error generating database name: %s", err)
+	}
+	name := t.name + "_" + hex.EncodeToString(suffix)
+
+	if _, err := t.admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", quoteIdentifier(name),
+		quoteIdentifier(t.name))); err != nil {
+		tb.Fatalf("error creating database '%s': %s", name, err)
+	}
+
+	dsn := t.dsn(name)
+	sqldb, err := sql.Open(t.driverName, dsn)
+	if err != nil {
+		tb.Fatalf("error opening database '%s': %s", name, err)
+	}
+
+	tb.Cleanup(func() {
+		_ = sqldb.Close()
+		if _, err := t.admin.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)",
+			quoteIdentifier(name))); err != nil {
+			tb.Errorf("error dropping database '%s': %s", name, err)
+		}
+	})
+
+	return sqldb, dsn
+}
+
+const templateCommentPrefix = "debefix:"
+
+func (t *Template) prepare(ctx context.Context) (err error) {
+	hash, err := t.Hash()
+	if err != nil {
+		return err
+	}
+
+	// a single connection is needed for the session advisory lock.
+	conn, err := t.admin.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lockID := templateLockID(t.name)
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("error locking template: %w", err)
+	}
+	defer func() {
+		_, uerr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+		err = errors.Join(err, uerr)
+	}()
+
+	var comment sql.NullString
+	err = conn.QueryRowContext(ctx, `SELECT pg_catalog.shobj_description(oid, 'pg_database')
+		FROM pg_catalog.pg_database WHERE datname = $1`, t.name).Scan(&comment)
+	switch {
+	case err == nil:
+		if comment.String == templateCommentPrefix+hash {
+			return nil
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s IS_TEMPLATE false",
+			quoteIdentifier(t.name))); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP DATABASE %s WITH (FORCE)",
+			quoteIdentifier(t.name))); err != nil {
+			return err
+		}
+	case errors.Is(err, sql.ErrNoRows):
+	default:
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s", quoteIdentifier(t.name))); err != nil {
+		return err
+	}
+	if err := t.build(ctx); err != nil {
+		// don't leave an incomplete template.
+		_, derr := conn.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)",
+			quoteIdentifier(t.name)))
+		return errors.Join(fmt.Errorf("error building template: %w", err), derr)
+	}
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s IS_TEMPLATE true",
+		quoteIdentifier(t.name))); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("COMMENT ON DATABASE %s IS '%s'", quoteIdentifier(t.name),
+		strings.ReplaceAll(templateCommentPrefix+hash, "'", "''")))
+	return err
+}
+
+// build creates the schema and resolves the data in the template database.
+func (t *Template) build(ctx context.Context) error {
+	sqldb, err := sql.Open(t.driverName, t.dsn(t.name))
+	if err != nil {
+		return err
+	}
+	defer sqldb.Close()
+
+	for _, ddl := range t.options.ddl {
+		if _, err := sqldb.ExecContext(ctx, ddl); err != nil {
+			return err
+		}
+	}
+	if t.options.schemaFunc != nil {
+		if err := t.options.schemaFunc(ctx, sqldb); err != nil {
+			return err
+		}
+	}
+
+	resolveCallback := t.options.resolveCallback
+	if resolveCallback == nil {
+		resolveCallback = func(db dbsql.DB) debefix.ResolveCallback {
+			return postgres.ResolveFunc(dbsql.NewSQLQueryInterface(db))
+		}
+	}
+
+	_, err = debefix.Resolve(ctx, t.data, resolveCallback(sqldb))
+	return err
+}
+
+// templateLockID returns the advisory lock ID of a template.
+func templateLockID(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("debefix-template:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,110 @@
+package pgtest
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func templateTestData() *debefix.Data {
+	data := debefix.NewData()
+	data.AddValues(debefix.TableName("tags"),
+		debefix.MapValues{"tag_id": 1, "name": "Go"},
+	)
+	return data
+}
+
+func TestTemplatePrepare(t *testing.T) {
+	ctx := context.Background()
+
+	admin, adminMock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer admin.Close()
+
+	_, templateMock, err := dbmock.NewWithDSN("pgtest_template_build")
+	assert.NilError(t, err)
+
+	tmpl := NewTemplate(admin, "dbmock", func(database string) string {
+		return "pgtest_" + database
+	}, "template_build", templateTestData(), WithTemplateDDL("CREATE TABLE tags (tag_id int, name text)"),
+		WithTemplateResolveCallback(func(db dbsql.DB) debefix.ResolveCallback {
+			return postgres.ResolveFunc(dbsql.NewSQLQueryInterface(db))
+		}))
+
+	hash, err := tmpl.Hash()
+	assert.NilError(t, err)
+
+	adminMock.ExpectExec(`SELECT pg_advisory_lock`).WithArgs(templateLockID("template_build")).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectQuery(`shobj_description`).WithArgs("template_build").
+		WillReturnRows(dbmock.NewRows("comment").AddRow("debefix:old"))
+	adminMock.ExpectExec(`ALTER DATABASE "template_build" IS_TEMPLATE false`).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectExec(regexp.QuoteMeta(`DROP DATABASE "template_build" WITH (FORCE)`)).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectExec(`CREATE DATABASE "template_build"`).WillReturnResult(dbmock.NewResult(0, 0))
+	templateMock.ExpectExec(`CREATE TABLE tags`).WillReturnResult(dbmock.NewResult(0, 0))
+	templateMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "tags" ("name", "tag_id") VALUES ($1, $2)`)).
+		WithArgs("Go", 1).WillReturnResult(dbmock.NewResult(0, 1))
+	adminMock.ExpectExec(`ALTER DATABASE "template_build" IS_TEMPLATE true`).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectExec(regexp.QuoteMeta(`COMMENT ON DATABASE "template_build" IS 'debefix:` + hash + `'`)).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(dbmock.NewResult(0, 0))
+
+	assert.NilError(t, tmpl.Prepare(ctx))
+	// only prepared once.
+	assert.NilError(t, tmpl.Prepare(ctx))
+
+	assert.NilError(t, adminMock.ExpectationsWereMet())
+	assert.NilError(t, templateMock.ExpectationsWereMet())
+}
+
+func TestTemplateNewDatabase(t *testing.T) {
+	admin, adminMock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer admin.Close()
+
+	tmpl := NewTemplate(admin, "dbmock", func(database string) string {
+		return "pgtest_" + database
+	}, "template_reuse", templateTestData())
+
+	hash, err := tmpl.Hash()
+	assert.NilError(t, err)
+
+	adminMock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectQuery(`shobj_description`).WithArgs("template_reuse").
+		WillReturnRows(dbmock.NewRows("comment").AddRow("debefix:" + hash))
+	adminMock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(dbmock.NewResult(0, 0))
+	adminMock.ExpectExec(`CREATE DATABASE "template_reuse_[0-9a-f]{16}" TEMPLATE "template_reuse"`).
+		WillReturnResult(dbmock.NewResult(0, 0))
+
+	t.Run("test", func(t *testing.T) {
+		_, dsn := tmpl.NewDatabase(t)
+		assert.Assert(t, regexp.MustCompile(`^pgtest_template_reuse_[0-9a-f]{16}$`).MatchString(dsn))
+
+		adminMock.ExpectExec(regexp.QuoteMeta(`DROP DATABASE IF EXISTS "` + dsn[len("pgtest_"):] + `" WITH (FORCE)`)).
+			WillReturnResult(dbmock.NewResult(0, 0))
+	})
+
+	assert.NilError(t, adminMock.ExpectationsWereMet())
+}
+
+func TestTemplateHashDataVersion(t *testing.T) {
+	dsn := func(database string) string {
+		return "pgtest_" + database
+	}
+
+	hash1, err := NewTemplate(nil, "dbmock", dsn, "template_hash", templateTestData()).Hash()
+	assert.NilError(t, err)
+	hash2, err := NewTemplate(nil, "dbmock", dsn, "template_hash", templateTestData(),
+		WithTemplateDataVersion("2")).Hash()
+	assert.NilError(t, err)
+	assert.Assert(t, hash1 != hash2)
+}
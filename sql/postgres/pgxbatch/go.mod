@@ -0,0 +1,21 @@
+module github.com/rrgmc/debefix-db/sql/postgres/pgxbatch/v2
+
+go 1.23
+
+require (
+	github.com/jackc/pgx/v5 v5.7.2
+	github.com/rrgmc/debefix-db/v2 v2.1.0
+	github.com/rrgmc/debefix/v2 v2.0.6
+	gotest.tools/v3 v3.5.1
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
+
+replace github.com/rrgmc/debefix-db/v2 => ../../..
@@ -0,0 +1,212 @@
+package pgxbatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// DB is an abstraction over pgx connections which support batches, like [pgx.Conn], [pgx.Tx] or
+// pgxpool.Pool.
+type DB interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// QueryInterface is a sql.QueryInterface which queues the queries which don't return fields in a [pgx.Batch],
+// sending them in a single round trip. The batch is sent when a query needs returned fields, which is added to the
+// batch, or when the maximum batch size is reached.
+// As errors of queued queries are only returned when the batch is sent, they may be returned by a query of another
+// row or table, so they are returned as a *BatchQueryError with the query which failed.
+// Use Resolve to resolve the data, which sends the remaining queries when the resolve finishes. If
+// [debefix.Resolve] is called directly, Process must be added to its options, or Flush called after it.
+type QueryInterface struct {
+	db           DB
+	maxBatchSize int
+
+	mu     sync.Mutex
+	queued []queuedQuery
+}
+
+var _ sql.QueryInterface = (*QueryInterface)(nil)
+
+type queuedQuery struct {
+	tableID debefix.TableID
+	query   string
+	args    []any
+}
+
+// New creates a QueryInterface.
+func New(db DB, options ...Option) *QueryInterface {
+	ret := &QueryInterface{
+		db:           db,
+		maxBatchSize: 100,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// Option is an option for New.
+type Option func(*QueryInterface)
+
+// WithMaxBatchSize sets the maximum number of queries in a batch. The default, also used if maxBatchSize is less
+// than 1, is 100.
+func WithMaxBatchSize(maxBatchSize int) Option {
+	return func(q *QueryInterface) {
+		if maxBatchSize > 0 {
+			q.maxBatchSize = maxBatchSize
+		}
+	}
+}
+
+// BatchQueryError is returned when a batched query fails. As the batch is sent by a later query, it contains the
+// information of the query which failed.
+type BatchQueryError struct {
+	TableID debefix.TableID
+	Query   string
+	Args    []any
+	Err     error
+}
+
+func (e *BatchQueryError) Error() string {
+	return fmt.Sprintf("error executing batched query for table '%s' `%s` with args %v: %s", e.TableID.TableID(),
+		e.Query, e.Args, e.Err)
+}
+
+func (e *BatchQueryError) Unwrap() error {
+	return e.Err
+}
+
+func (q *QueryInterface) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string,
+	args ...any) (map[string]any, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queued = append(q.queued, queuedQuery{tableID: tableID, query: query, args: args})
+	if len(returnFieldNames) == 0 {
+		if len(q.queued) < q.maxBatchSize {
+			return nil, nil
+		}
+		return nil, q.flush(ctx, nil)
+	}
+
+	var ret map[string]any
+	err := q.flush(ctx, func(rows pgx.Rows) error {
+		var err error
+		ret, err = rowToMap(rows)
+		return err
+	})
+	return ret, err
+}
+
+// Flush sends the queued queries.
+func (q *QueryInterface) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.flush(ctx, nil)
+}
+
+// Process returns a [debefix.Process] which calls Flush when the resolve finishes.
+func (q *QueryInterface) Process() debefix.Process {
+	return &flushProcess{q: q}
+}
+
+// Resolve calls [debefix.Resolve] with Process, so the remaining queries are sent when the resolve finishes. If the
+// resolve fails, the queries which were not sent are discarded.
+func (q *QueryInterface) Resolve(ctx context.Context, data *debefix.Data, resolveFunc debefix.ResolveCallback,
+	options ...debefix.ResolveOption) (*debefix.ResolvedData, error) {
+	resolved, err := debefix.Resolve(ctx, data, resolveFunc,
+		append(slices.Clone(options), debefix.WithResolveOptionProcess(q.Process()))...)
+	if err != nil {
+		q.mu.Lock()
+		q.queued = nil
+		q.mu.Unlock()
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// flush sends the queued queries. If readLast is not nil, the last query returns fields, which are read by it.
+func (q *QueryInterface) flush(ctx context.Context, readLast func(pgx.Rows) error) error {
+	if len(q.queued) == 0 {
+		return nil
+	}
+	queued := q.queued
+	q.queued = nil
+
+	batch := &pgx.Batch{}
+	for _, qq := range queued {
+		batch.Queue(qq.query, qq.args...)
+	}
+
+	br := q.db.SendBatch(ctx, batch)
+	var retErr error
+	for i, qq := range queued {
+		var err error
+		if readLast != nil && i == len(queued)-1 {
+			err = readQuery(br, readLast)
+		} else {
+			_, err = br.Exec()
+		}
+		if err != nil {
+			retErr = &BatchQueryError{TableID: qq.tableID, Query: qq.query, Args: qq.args, Err: err}
+			break
+		}
+	}
+	if retErr != nil {
+		// the batch error is also returned by Close.
+		_ = br.Close()
+		return retErr
+	}
+	return br.Close()
+}
+
+func readQuery(br pgx.BatchResults, read func(pgx.Rows) error) error {
+	rows, err := br.Query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+		return errors.New("no records on query")
+	}
+	if err := read(rows); err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+func rowToMap(rows pgx.Rows) (map[string]any, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]any{}
+	for i, fd := range rows.FieldDescriptions() {
+		ret[fd.Name] = values[i]
+	}
+	return ret, nil
+}
+
+type flushProcess struct {
+	q *QueryInterface
+}
+
+func (p *flushProcess) Start(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+func (p *flushProcess) Finish(ctx context.Context) error {
+	return p.q.Flush(ctx)
+}
@@ -0,0 +1,154 @@
+package pgxbatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestQueryInterface(t *testing.T) {
+	ctx := context.Background()
+
+	tableTags := debefix.TableName("public.tags")
+	tablePosts := debefix.TableName("public.posts")
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": 1,
+			"name":   "Go",
+		},
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("js"),
+			"name":   "JavaScript",
+		},
+	)
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "js", "tag_id"),
+		},
+		debefix.MapValues{
+			"post_id": 2,
+			"tag_id":  1,
+		},
+	)
+
+	db := &testDB{}
+	qi := New(db)
+
+	resolved, err := qi.Resolve(ctx, data, postgres.ResolveFunc(qi))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, [][]string{
+		{
+			`INSERT INTO "public.tags" ("name", "tag_id") VALUES ($1, $2)`,
+			`INSERT INTO "public.tags" ("name") VALUES ($1) RETURNING "tag_id"`,
+		},
+		{
+			`INSERT INTO "public.posts" ("post_id", "tag_id") VALUES ($1, $2)`,
+			`INSERT INTO "public.posts" ("post_id", "tag_id") VALUES ($1, $2)`,
+		},
+	}, db.batches)
+
+	tagID, err := resolved.FindRefIDRowValue(debefix.ValueRefID(tableTags, "js", "tag_id"))
+	assert.NilError(t, err)
+	assert.Equal(t, int32(10), tagID)
+
+	// errors of queued queries are returned by the next flush.
+	db = &testDB{execErr: errors.New("duplicate key")}
+	qi = New(db, WithMaxBatchSize(2))
+	_, err = qi.Query(ctx, tableTags, "INSERT 1", nil, 1)
+	assert.NilError(t, err)
+	_, err = qi.Query(ctx, tablePosts, "INSERT 2", nil, 2)
+	assert.Error(t, err, "error executing batched query for table 'public.tags' `INSERT 1` with args [1]: duplicate key")
+	var batchErr *BatchQueryError
+	assert.Assert(t, errors.As(err, &batchErr))
+	assert.Equal(t, tableTags.TableID(), batchErr.TableID.TableID())
+	assert.DeepEqual(t, []any{1}, batchErr.Args)
+	assert.NilError(t, qi.Flush(ctx))
+
+	// an invalid maximum batch size uses the default.
+	db = &testDB{}
+	qi = New(db, WithMaxBatchSize(0))
+	for range 3 {
+		_, err = qi.Query(ctx, tableTags, "INSERT", nil)
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, 0, len(db.batches))
+	assert.NilError(t, qi.Flush(ctx))
+	assert.Equal(t, 1, len(db.batches))
+}
+
+type testDB struct {
+	batches [][]string
+	execErr error
+}
+
+func (d *testDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	var queries []string
+	for _, qq := range b.QueuedQueries {
+		queries = append(queries, qq.SQL)
+	}
+	d.batches = append(d.batches, queries)
+	return &testBatchResults{execErr: d.execErr}
+}
+
+type testBatchResults struct {
+	execErr error
+}
+
+func (r *testBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("INSERT 0 1"), r.execErr
+}
+
+func (r *testBatchResults) Query() (pgx.Rows, error) {
+	return &testRows{}, nil
+}
+
+func (r *testBatchResults) QueryRow() pgx.Row {
+	return &testRows{}
+}
+
+func (r *testBatchResults) Close() error {
+	return r.execErr
+}
+
+// testRows returns a single row with a "tag_id" field.
+type testRows struct {
+	read bool
+}
+
+func (r *testRows) Close()                        {}
+func (r *testRows) Err() error                    { return nil }
+func (r *testRows) CommandTag() pgconn.CommandTag { return pgconn.NewCommandTag("INSERT 0 1") }
+func (r *testRows) RawValues() [][]byte           { return nil }
+func (r *testRows) Conn() *pgx.Conn               { return nil }
+
+func (r *testRows) FieldDescriptions() []pgconn.FieldDescription {
+	return []pgconn.FieldDescription{{Name: "tag_id"}}
+}
+
+func (r *testRows) Next() bool {
+	if r.read {
+		return false
+	}
+	r.read = true
+	return true
+}
+
+func (r *testRows) Scan(dest ...any) error {
+	*(dest[0].(*int32)) = 10
+	return nil
+}
+
+func (r *testRows) Values() ([]any, error) {
+	return []any{int32(10)}, nil
+}
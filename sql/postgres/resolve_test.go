@@ -2,9 +2,13 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
 	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/sqltest"
 	"github.com/rrgmc/debefix/v2"
 	"gotest.tools/v3/assert"
 )
@@ -331,3 +335,87 @@ func TestResolveUpdateOrder(t *testing.T) {
 
 	assert.DeepEqual(t, expectedQueryList, queryList)
 }
+
+func TestResolveGolden(t *testing.T) {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":     debefix.ResolveValueResolve(),
+			"_refid":     debefix.SetValueRefID("all"),
+			"tag_name":   "All",
+			"created_at": debefix.ValueBaseTimeAdd(debefix.WithAddHours(-1)),
+		},
+	)
+
+	postIID := data.AddWithID(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "all", "tag_id"),
+			"title":   "First post",
+		},
+	)
+
+	data.AddValues(tablePostTags,
+		debefix.MapValues{
+			"post_id": postIID.ValueForField("post_id"),
+			"tag_id":  debefix.ValueRefID(tableTags, "all", "tag_id"),
+		},
+	)
+
+	data.Update(postIID.UpdateQuery([]string{"post_id"}),
+		debefix.UpdateActionSetValues{Values: debefix.MapValues{
+			"title":      "First post updated",
+			"updated_at": debefix.ValueBaseTimeAdd(),
+		}})
+
+	sqltest.AssertGolden(t, data, QueryBuilder(), "resolve.golden")
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "postgres error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestRetryClassifier(t *testing.T) {
+	assert.Equal(t, sql.RetryErrorTransient, RetryClassifier(fmt.Errorf("query: %w", sqlStateError("40001"))))
+	assert.Equal(t, sql.RetryErrorTransient, RetryClassifier(sqlStateError("40P01")))
+	assert.Equal(t, sql.RetryErrorConnection, RetryClassifier(sqlStateError("08006")))
+	assert.Equal(t, sql.RetryErrorConnection, RetryClassifier(sqlStateError("57P03")))
+	assert.Equal(t, sql.RetryErrorNone, RetryClassifier(sqlStateError("23505")))
+	assert.Equal(t, sql.RetryErrorNone, RetryClassifier(errors.New("other")))
+}
+
+func TestTimeoutDiagnostics(t *testing.T) {
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	query := `INSERT INTO "public.tags" ("tag_id") VALUES ($1)`
+
+	mock.ExpectQuery("pg_blocking_pids").WithArgs(query).WillReturnRows(
+		dbmock.NewRows("pid", "blocking_pid", "state", "query", "locks").
+			AddRow(10, 20, "idle in transaction", "LOCK TABLE tags", "relation tags RowExclusiveLock"))
+
+	diagnostics, err := TimeoutDiagnostics(db)(context.Background(), tableTags, query)
+	assert.NilError(t, err)
+	assert.Equal(t, "pid 10 waiting for relation tags RowExclusiveLock blocked by pid 20 (idle in transaction): "+
+		"LOCK TABLE tags", diagnostics)
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
+
+func TestSeedLocker(t *testing.T) {
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(seedLockID("tags")).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(seedLockID("tags")).
+		WillReturnResult(dbmock.NewResult(0, 0))
+
+	unlock, err := SeedLocker(db).LockSeed(context.Background(), "tags")
+	assert.NilError(t, err)
+	assert.NilError(t, unlock(context.Background()))
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// RetryClassifier is a sql.RetryClassifier for postgres errors. It supports driver errors which have a
+// "SQLState() string" method, like the ones from pgx and lib/pq.
+// Serialization failures (40001) and deadlocks (40P01) are transient errors, and connection exceptions (08xxx)
+// and server startup and shutdown errors (57P01, 57P02, 57P03) are connection errors.
+func RetryClassifier(err error) sql.RetryErrorKind {
+	var stateErr interface {
+		SQLState() string
+	}
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		switch {
+		case state == "40001", state == "40P01":
+			return sql.RetryErrorTransient
+		case strings.HasPrefix(state, "08"), state == "57P01", state == "57P02", state == "57P03":
+			return sql.RetryErrorConnection
+		}
+	}
+	return sql.DefaultRetryClassifier(err)
+}
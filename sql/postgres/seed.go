@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// SeedLocker returns a sql.SeedLocker which uses a postgres session advisory lock for each seed name.
+// As the lock is held by the session, if db is a connection pool, like [database/sql.DB], a dedicated connection is
+// used while the lock is held.
+func SeedLocker(db sql.DB) sql.SeedLocker {
+	return sql.SeedLockerFunc(func(ctx context.Context, name string) (func(ctx context.Context) error, error) {
+		session, release, err := sql.SessionDB(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		lockID := seedLockID(name)
+		if _, err := session.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+			return nil, errors.Join(err, release())
+		}
+		return func(ctx context.Context) error {
+			_, err := session.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID)
+			return errors.Join(err, release())
+		}, nil
+	})
+}
+
+// SeedLocker returns the SeedLocker function of the package.
+func (d QueryBuilderDialect) SeedLocker(db sql.DB) sql.SeedLocker {
+	return SeedLocker(db)
+}
+
+// seedLockID returns the advisory lock ID of a seed name.
+func seedLockID(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("debefix-seed:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// blockingQuery returns the sessions blocking the sessions executing a query, with the locks they are waiting for.
+const blockingQuery = `SELECT w.pid, b.pid, COALESCE(b.state, ''), COALESCE(b.query, ''),
+	COALESCE((SELECT string_agg(DISTINCT l.locktype || COALESCE(' ' || l.relation::regclass::text, '') || ' ' || l.mode, ', ')
+		FROM pg_locks l WHERE l.pid = w.pid AND NOT l.granted), '')
+FROM pg_stat_activity w
+CROSS JOIN LATERAL unnest(pg_blocking_pids(w.pid)) AS bp(pid)
+JOIN pg_stat_activity b ON b.pid = bp.pid
+WHERE w.query = $1 AND w.pid <> pg_backend_pid()
+ORDER BY w.pid, b.pid`
+
+// TimeoutDiagnostics returns a sql.TimeoutDiagnostics which lists the sessions blocking the statement which timed
+// out, using pg_stat_activity, pg_locks and pg_blocking_pids.
+// db must not be the connection or transaction executing the statement, as it is blocked. Usually it is the
+// [sql.DB] connection pool.
+func TimeoutDiagnostics(db sql.DB) sql.TimeoutDiagnostics {
+	return func(ctx context.Context, tableID debefix.TableID, query string) (string, error) {
+		rows, err := db.QueryContext(ctx, blockingQuery, query)
+		if err != nil {
+			return "", err
+		}
+		defer rows.Close()
+
+		var lines []string
+		for rows.Next() {
+			var pid, blockingPID int64
+			var state, blockingQuery, locks string
+			if err := rows.Scan(&pid, &blockingPID, &state, &blockingQuery, &locks); err != nil {
+				return "", err
+			}
+			line := fmt.Sprintf("pid %d", pid)
+			if locks != "" {
+				line += fmt.Sprintf(" waiting for %s", locks)
+			}
+			line += fmt.Sprintf(" blocked by pid %d (%s): %s", blockingPID, state, blockingQuery)
+			lines = append(lines, line)
+		}
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		if len(lines) == 0 {
+			return "no blocking sessions found", nil
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
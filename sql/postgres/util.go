@@ -5,3 +5,12 @@ import "strings"
 func quoteIdentifier(s string) string {
 	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
 }
+
+// arrayLiteral returns a postgres array literal of the strings.
+func arrayLiteral(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(item) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
@@ -11,15 +11,28 @@ import (
 )
 
 // ResolveDBFunc is a db.ResolveDBCallback helper to generate SQL database records.
+// The context passed to the QueryInterface contains a QueryInfo, which can be read with QueryInfoFromContext.
 func ResolveDBFunc(qi QueryInterface, queryBuilder QueryBuilder) db.ResolveDBCallback {
 	return func(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
 		returnFieldNames map[string]debefix.ResolveValue) (returnValues map[string]any, err error) {
+		queryInfo := QueryInfo{
+			ResolveDBInfo: resolveInfo,
+		}
 
-		query, args, err := queryBuilder.BuildSQL(ctx, resolveInfo, fields, returnFieldNames)
+		var query string
+		var args []any
+		if qb, ok := queryBuilder.(QueryBuilderArgFieldNames); ok {
+			query, args, queryInfo.ArgFieldNames, err = qb.BuildSQLArgFieldNames(ctx, resolveInfo, fields,
+				returnFieldNames)
+		} else {
+			query, args, err = queryBuilder.BuildSQL(ctx, resolveInfo, fields, returnFieldNames)
+		}
 		if err != nil {
 			return nil, err
 		}
 
+		ctx = ContextWithQueryInfo(ctx, queryInfo)
+
 		ret, err := qi.Query(ctx, resolveInfo.TableID, query, slices.Collect(maps.Keys(returnFieldNames)), args...)
 		if err != nil {
 			return nil, fmt.Errorf("error executing query `%s`: %w", query, err)
@@ -1,9 +1,19 @@
 package sql
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
 	"github.com/rrgmc/debefix/v2"
 	"gotest.tools/v3/assert"
 )
@@ -330,3 +340,303 @@ func TestResolveUpdateOrder(t *testing.T) {
 
 	assert.DeepEqual(t, expectedQueryList, queryList)
 }
+
+func TestDebugMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	var calls []string
+
+	middleware := func(name string) QueryInterfaceMiddleware {
+		return func(next QueryInterface) QueryInterface {
+			return QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+				returnFieldNames []string, args ...any) (map[string]any, error) {
+				calls = append(calls, name)
+				return next.Query(ctx, tableID, query, returnFieldNames, args...)
+			})
+		}
+	}
+
+	qi := Chain(middleware("first"), DebugMiddleware(&out), middleware("second"))(
+		QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string,
+			args ...any) (map[string]any, error) {
+			calls = append(calls, "query")
+			return map[string]any{"tag_id": 10}, nil
+		}))
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("all"),
+			"tag_name": "All",
+		},
+	)
+
+	resolved, err := debefix.Resolve(ctx, data, ResolveFunc(qi, NewQueryBuilder(DefaultQueryBuilderDialect{})))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{"first", "second", "query"}, calls)
+
+	tagID, err := resolved.FindRefIDRowValue(debefix.ValueRefID(tableTags, "all", "tag_id"))
+	assert.NilError(t, err)
+	assert.Equal(t, 10, tagID)
+
+	assert.Assert(t, strings.Contains(out.String(),
+		"INSERT INTO public.tags (tag_name) VALUES (?) RETURNING tag_id\n$$ ARGS: [0:\"All\"]\n"), out.String())
+}
+
+func TestDebugMiddlewareParallel(t *testing.T) {
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	qi := NewDebugQueryInterface(&out)
+
+	data := debefix.NewData()
+	data.AddValues(tableTags, debefix.MapValues{"tag_id": 1, "tag_name": "All"})
+	data.AddValues(tablePosts, debefix.MapValues{"post_id": 1, "title": "First post"})
+
+	_, err := db.ResolveParallel(ctx, data, func(ctx context.Context) (db.ResolveDBCallback, func() error, error) {
+		return ResolveDBFunc(qi, NewQueryBuilder(DefaultQueryBuilderDialect{})), func() error { return nil }, nil
+	})
+	assert.NilError(t, err)
+
+	// the output of each query is not interleaved.
+	assert.Assert(t, strings.Contains(out.String(), "=============== public.tags ===============\n"+
+		"INSERT INTO public.tags (tag_id, tag_name) VALUES (?, ?)\n$$ ARGS: [0:\"1\"] [1:\"All\"]\n"), out.String())
+	assert.Assert(t, strings.Contains(out.String(), "=============== public.posts ===============\n"+
+		"INSERT INTO public.posts (post_id, title) VALUES (?, ?)\n"), out.String())
+}
+
+func TestSlogMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	qi := SlogMiddleware(logger, WithSlogRedactFields(tableTags, "secret"))(
+		QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string,
+			args ...any) (map[string]any, error) {
+			if tableID.TableID() == tablePosts.TableID() {
+				return nil, errors.New("insert failed")
+			}
+			return map[string]any{"tag_id": 10}, nil
+		}))
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("all"),
+			"tag_name": "All",
+			"secret":   "s3cr3t",
+		},
+	)
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "all", "tag_id"),
+		},
+	)
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(qi, NewQueryBuilder(DefaultQueryBuilderDialect{})))
+	assert.ErrorContains(t, err, "insert failed")
+
+	var entries []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var entry map[string]any
+		assert.NilError(t, dec.Decode(&entry))
+		delete(entry, "time")
+		delete(entry, "duration")
+		entries = append(entries, entry)
+	}
+
+	assert.DeepEqual(t, []map[string]any{
+		{
+			"level":        "DEBUG",
+			"msg":          "debefix query",
+			"table_id":     "public.tags",
+			"resolve_type": "add",
+			"arg_count":    float64(2),
+			"args": map[string]any{
+				"secret":   SlogRedacted,
+				"tag_name": "All",
+			},
+			"return_field_names": []any{"tag_id"},
+		},
+		{
+			"level":        "ERROR",
+			"msg":          "debefix query error",
+			"table_id":     "public.posts",
+			"resolve_type": "add",
+			"arg_count":    float64(2),
+			"args": map[string]any{
+				"post_id": float64(1),
+				"tag_id":  float64(10),
+			},
+			"error": "insert failed",
+		},
+	}, entries)
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	blockingQI := QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		if query == "FAST" {
+			return map[string]any{"tag_id": 1}, nil
+		}
+		<-ctx.Done()
+		return nil, errors.New("canceling statement due to user request")
+	})
+
+	var diagnosticsCtxErr error
+	qi := TimeoutMiddleware(10*time.Millisecond, WithTimeoutDiagnostics(func(ctx context.Context,
+		tableID debefix.TableID, query string) (string, error) {
+		diagnosticsCtxErr = ctx.Err()
+		return "blocked by pid 10", nil
+	}))(blockingQI)
+
+	ret, err := qi.Query(ctx, tableTags, "FAST", []string{"tag_id"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]any{"tag_id": 1}, ret)
+
+	_, err = qi.Query(ctx, tableTags, "INSERT INTO public.tags", nil)
+	assert.Error(t, err, "statement on table 'public.tags' timed out after 10ms: `INSERT INTO public.tags`\n"+
+		"blocked by pid 10")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NilError(t, diagnosticsCtxErr)
+
+	var timeoutErr *TimeoutError
+	assert.Assert(t, errors.As(err, &timeoutErr))
+	assert.Equal(t, "blocked by pid 10", timeoutErr.Diagnostics)
+
+	// cancellations of the parent context are not timeouts.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = TimeoutMiddleware(time.Second)(blockingQI).Query(cctx, tableTags, "INSERT INTO public.tags", nil)
+	assert.Error(t, err, "canceling statement due to user request")
+}
+
+type countPrepareDB struct {
+	*sql.DB
+	prepared []string
+}
+
+func (d *countPrepareDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	d.prepared = append(d.prepared, query)
+	return d.DB.PrepareContext(ctx, query)
+}
+
+func TestSQLQueryInterfaceStatementCache(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	for i, name := range []string{"Go", "JavaScript", "C++"} {
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO tags (name) VALUES (?) RETURNING tag_id")).WithArgs(name).
+			WillReturnRows(dbmock.NewRows("tag_id").AddRow(i + 1))
+	}
+	for i, tagID := range []int{1, 3} {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO posts (post_id, tag_id) VALUES (?, ?)")).
+			WithArgs(i+1, tagID).WillReturnResult(dbmock.NewResult(0, 1))
+	}
+
+	tableTags := debefix.TableName("tags")
+	tablePosts := debefix.TableName("posts")
+
+	data := debefix.NewData()
+	for _, name := range []debefix.RefID{"Go", "JavaScript", "C++"} {
+		data.AddValues(tableTags, debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID(name),
+			"name":   name,
+		})
+	}
+	for i, name := range []debefix.RefID{"Go", "C++"} {
+		data.AddValues(tablePosts, debefix.MapValues{
+			"post_id": i + 1,
+			"tag_id":  debefix.ValueRefID(tableTags, name, "tag_id"),
+		})
+	}
+
+	db := &countPrepareDB{DB: sqldb}
+	qi := NewSQLQueryInterface(db, WithStatementCache(1))
+	cache := qi.(*sqlQueryInterface).stmtCache
+
+	_, err = ResolveClose(ctx, data, qi, ResolveFunc(qi, NewQueryBuilder(DefaultQueryBuilderDialect{})))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{
+		"INSERT INTO tags (name) VALUES (?) RETURNING tag_id",
+		"INSERT INTO posts (post_id, tag_id) VALUES (?, ?)",
+	}, db.prepared)
+	assert.Equal(t, 0, cache.len())
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	_, err = qi.Query(ctx, tableTags, "SELECT 1", nil)
+	assert.ErrorContains(t, err, "statement cache is closed")
+}
+
+func TestSQLQueryInterfaceStatementCacheErrors(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	// DB without PrepareContext.
+	qi := NewSQLQueryInterface(struct{ DB }{sqldb}, WithStatementCache(0))
+	_, err = qi.Query(ctx, tableTags, "SELECT 1", nil)
+	assert.ErrorContains(t, err, "statement cache requires a DB which implements PrepareContext")
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (name) VALUES (?)")).WithArgs("Go").
+		WillReturnError(errors.New("insert failed"))
+
+	data := debefix.NewData()
+	data.AddValues(debefix.TableName("tags"), debefix.MapValues{"name": "Go"})
+
+	qi = NewSQLQueryInterface(sqldb, WithStatementCache(0))
+	cache := qi.(*sqlQueryInterface).stmtCache
+
+	_, err = ResolveClose(ctx, data, qi, ResolveFunc(qi, NewQueryBuilder(DefaultQueryBuilderDialect{})))
+	assert.ErrorContains(t, err, "insert failed")
+	// closed even if the resolve failed.
+	assert.Equal(t, 0, cache.len())
+	assert.Assert(t, cache.closed)
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
+
+func TestRouteQueryInterface(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	qi := func(name string) QueryInterface {
+		return QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+			returnFieldNames []string, args ...any) (map[string]any, error) {
+			calls = append(calls, name+": "+query)
+			return nil, nil
+		})
+	}
+
+	rqi := RouteQueryInterface(
+		QueryRoute{Match: db.MatchTables("public.posts"), QueryInterface: qi("posts")},
+		QueryRoute{Match: db.MatchSchemas("public"), QueryInterface: qi("public")},
+	)
+
+	_, err := rqi.Query(ctx, tableTags, "INSERT tags", nil)
+	assert.NilError(t, err)
+	_, err = rqi.Query(ctx, tablePosts, "INSERT posts", nil)
+	assert.NilError(t, err)
+	_, err = rqi.Query(ctx, debefix.TableName("audit.events"), "INSERT events", nil)
+	assert.ErrorIs(t, err, db.ErrNoRoute)
+	assert.ErrorContains(t, err, "no route for table 'audit.events'")
+
+	assert.DeepEqual(t, []string{"public: INSERT tags", "posts: INSERT posts"}, calls)
+}
@@ -0,0 +1,217 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// RetryErrorKind is the kind of error returned by a RetryClassifier.
+type RetryErrorKind int
+
+const (
+	RetryErrorNone       RetryErrorKind = iota // the error is not retryable.
+	RetryErrorTransient                        // a transient error like a deadlock or serialization failure.
+	RetryErrorConnection                       // a connection error, which can't be retried inside a transaction.
+)
+
+// RetryClassifier returns the kind of error, to decide whether it can be retried.
+type RetryClassifier func(err error) RetryErrorKind
+
+// DefaultRetryClassifier classifies network errors and [driver.ErrBadConn] as connection errors. The dialect
+// packages have classifiers for database-specific errors which fallback to this one.
+func DefaultRetryClassifier(err error) RetryErrorKind {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return RetryErrorNone
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &netErr):
+		return RetryErrorConnection
+	}
+	return RetryErrorNone
+}
+
+// RetryMiddleware returns a QueryInterfaceMiddleware which retries queries which failed with retryable errors,
+// with exponential backoff.
+// If the queries are executed inside a transaction, WithRetrySavepoint must be set, so the failed statement can be
+// rolled back without aborting the transaction.
+func RetryMiddleware(options ...RetryOption) QueryInterfaceMiddleware {
+	optns := newRetryOptions(options...)
+	return func(next QueryInterface) QueryInterface {
+		return &retryQueryInterface{options: optns, next: next}
+	}
+}
+
+// WaitReady pings the database until it succeeds, retrying the errors classified as retryable, with exponential
+// backoff. It is useful to wait for freshly started database containers to accept connections.
+// If WithRetryMaxAttempts is not set, it retries until the context is done.
+func WaitReady(ctx context.Context, db interface {
+	PingContext(ctx context.Context) error
+}, options ...RetryOption) error {
+	optns := newRetryOptions(append([]RetryOption{WithRetryMaxAttempts(0)}, options...)...)
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if optns.classifier(err) == RetryErrorNone {
+			return err
+		}
+		if optns.maxAttempts > 0 && attempt >= optns.maxAttempts {
+			return fmt.Errorf("database not ready after %d attempts: %w", attempt, err)
+		}
+		if serr := optns.sleep(ctx, attempt); serr != nil {
+			return fmt.Errorf("database not ready: %w", errors.Join(serr, err))
+		}
+	}
+}
+
+// RetryOption is an option for RetryMiddleware and WaitReady.
+type RetryOption func(*retryOptions)
+
+// WithRetryClassifier sets the error classifier. The default is DefaultRetryClassifier.
+func WithRetryClassifier(classifier RetryClassifier) RetryOption {
+	return func(o *retryOptions) {
+		o.classifier = classifier
+	}
+}
+
+// WithRetryMaxAttempts sets the maximum number of attempts, including the first one. The default is 5.
+// Zero means no limit.
+func WithRetryMaxAttempts(maxAttempts int) RetryOption {
+	return func(o *retryOptions) {
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetryBackoff sets the initial and maximum wait between attempts. The wait doubles on each attempt. The
+// default is 100ms to 5s.
+func WithRetryBackoff(initial, max time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.initialBackoff = initial
+		o.maxBackoff = max
+	}
+}
+
+// WithRetrySavepoint sets the transaction where the queries are executed. A savepoint is created before each
+// statement, and rolled back when it fails, so it can be retried. Connection errors are not retried in this mode,
+// as the transaction is lost.
+func WithRetrySavepoint(tx DB) RetryOption {
+	return func(o *retryOptions) {
+		o.tx = tx
+	}
+}
+
+type retryOptions struct {
+	classifier     RetryClassifier
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	tx             DB
+}
+
+func newRetryOptions(options ...RetryOption) retryOptions {
+	ret := retryOptions{
+		classifier:     DefaultRetryClassifier,
+		maxAttempts:    5,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     5 * time.Second,
+	}
+	for _, opt := range options {
+		opt(&ret)
+	}
+	return ret
+}
+
+// sleep waits the backoff of the attempt, or until the context is done.
+func (o retryOptions) sleep(ctx context.Context, attempt int) error {
+	backoff := o.initialBackoff
+	for i := 1; i < attempt && backoff < o.maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, o.maxBackoff)
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+const retrySavepointName = "debefix_retry"
+
+type retryQueryInterface struct {
+	options retryOptions
+	next    QueryInterface
+}
+
+func (r *retryQueryInterface) Query(ctx context.Context, tableID debefix.TableID, query string,
+	returnFieldNames []string, args ...any) (map[string]any, error) {
+	for attempt := 1; ; attempt++ {
+		ret, err := r.attempt(ctx, tableID, query, returnFieldNames, args...)
+		if err == nil {
+			return ret, nil
+		}
+
+		kind := r.options.classifier(err)
+		if kind == RetryErrorNone || (kind == RetryErrorConnection && r.options.tx != nil) {
+			return nil, err
+		}
+		if r.options.maxAttempts > 0 && attempt >= r.options.maxAttempts {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		if serr := r.options.sleep(ctx, attempt); serr != nil {
+			return nil, errors.Join(serr, err)
+		}
+	}
+}
+
+// attempt executes the query once, inside a savepoint if in a transaction.
+func (r *retryQueryInterface) attempt(ctx context.Context, tableID debefix.TableID, query string,
+	returnFieldNames []string, args ...any) (map[string]any, error) {
+	if r.options.tx == nil {
+		return r.next.Query(ctx, tableID, query, returnFieldNames, args...)
+	}
+
+	if _, err := r.options.tx.ExecContext(ctx, "SAVEPOINT "+retrySavepointName); err != nil {
+		return nil, fmt.Errorf("error creating savepoint: %w", err)
+	}
+	ret, err := r.next.Query(ctx, tableID, query, returnFieldNames, args...)
+	if err != nil {
+		if _, rerr := r.options.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+retrySavepointName); rerr != nil {
+			// the transaction is unusable, don't retry.
+			return nil, fmt.Errorf("error rolling back to savepoint: %w",
+				errors.Join(rerr, retryNoneError{err}))
+		}
+		return nil, err
+	}
+	if _, err := r.options.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+retrySavepointName); err != nil {
+		return nil, fmt.Errorf("error releasing savepoint: %w", err)
+	}
+	return ret, nil
+}
+
+// retryNoneError hides the wrapped error from errors.Is/As, so it is not classified as retryable.
+type retryNoneError struct {
+	err error
+}
+
+func (e retryNoneError) Error() string {
+	return e.err.Error()
+}
@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var errRetryTransient = errors.New("transient")
+
+func retryTestClassifier(err error) RetryErrorKind {
+	if errors.Is(err, errRetryTransient) {
+		return RetryErrorTransient
+	}
+	return DefaultRetryClassifier(err)
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		name         string
+		errs         []error
+		expectedErr  string
+		expectedCall int
+	}{
+		{
+			name:         "transient",
+			errs:         []error{errRetryTransient, driver.ErrBadConn},
+			expectedCall: 3,
+		},
+		{
+			name:         "not retryable",
+			errs:         []error{errors.New("syntax error")},
+			expectedErr:  "syntax error",
+			expectedCall: 1,
+		},
+		{
+			name:         "max attempts",
+			errs:         []error{errRetryTransient, errRetryTransient, errRetryTransient},
+			expectedErr:  "giving up after 3 attempts: transient",
+			expectedCall: 3,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			qi := RetryMiddleware(
+				WithRetryClassifier(retryTestClassifier),
+				WithRetryMaxAttempts(3),
+				WithRetryBackoff(time.Millisecond, time.Millisecond),
+			)(QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+				returnFieldNames []string, args ...any) (map[string]any, error) {
+				calls++
+				if calls <= len(test.errs) {
+					return nil, test.errs[calls-1]
+				}
+				return map[string]any{"tag_id": 1}, nil
+			}))
+
+			ret, err := qi.Query(ctx, tableTags, "INSERT", []string{"tag_id"})
+			assert.Equal(t, test.expectedCall, calls)
+			if test.expectedErr != "" {
+				assert.Error(t, err, test.expectedErr)
+			} else {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, map[string]any{"tag_id": 1}, ret)
+			}
+		})
+	}
+}
+
+func TestRetryMiddlewareSavepoint(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	// the first attempt inserts the row and fails, so it must be rolled back to the savepoint.
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT debefix_retry").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (tag_id, name) VALUES (?, ?)")).WithArgs(1, "Go").
+		WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT debefix_retry").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT debefix_retry").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (tag_id, name) VALUES (?, ?)")).WithArgs(1, "Go").
+		WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT debefix_retry").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT debefix_retry").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT debefix_retry").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := sqldb.BeginTx(ctx, nil)
+	assert.NilError(t, err)
+
+	calls := 0
+	qi := RetryMiddleware(
+		WithRetryClassifier(retryTestClassifier),
+		WithRetryBackoff(time.Millisecond, time.Millisecond),
+		WithRetrySavepoint(tx),
+	)(QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		calls++
+		_, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		if calls == 1 {
+			return nil, errRetryTransient
+		}
+		return nil, nil
+	}))
+
+	_, err = qi.Query(ctx, tableTags, "INSERT INTO tags (tag_id, name) VALUES (?, ?)", nil, 1, "Go")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// connection errors are not retried inside transactions.
+	calls = 0
+	qi = RetryMiddleware(WithRetrySavepoint(tx))(QueryInterfaceFunc(func(ctx context.Context,
+		tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+		calls++
+		return nil, driver.ErrBadConn
+	}))
+	_, err = qi.Query(ctx, tableTags, "INSERT", nil)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Equal(t, 1, calls)
+
+	assert.NilError(t, tx.Commit())
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
+
+type retryTestPinger struct {
+	errs []error
+}
+
+func (p *retryTestPinger) PingContext(ctx context.Context) error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return err
+}
+
+func TestWaitReady(t *testing.T) {
+	ctx := context.Background()
+
+	err := WaitReady(ctx, &retryTestPinger{errs: []error{driver.ErrBadConn, driver.ErrBadConn}},
+		WithRetryBackoff(time.Millisecond, time.Millisecond))
+	assert.NilError(t, err)
+
+	err = WaitReady(ctx, &retryTestPinger{errs: []error{driver.ErrBadConn, driver.ErrBadConn}},
+		WithRetryBackoff(time.Millisecond, time.Millisecond), WithRetryMaxAttempts(2))
+	assert.ErrorContains(t, err, "database not ready after 2 attempts")
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = WaitReady(ctx, &retryTestPinger{errs: []error{driver.ErrBadConn, driver.ErrBadConn}},
+		WithRetryBackoff(time.Second, time.Second))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
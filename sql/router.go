@@ -0,0 +1,31 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// QueryRoute is a route of RouteQueryInterface.
+type QueryRoute struct {
+	Match          db.TableMatcher
+	QueryInterface QueryInterface
+}
+
+// RouteQueryInterface returns a QueryInterface which executes the queries using the QueryInterface of the first
+// route matching the table. As the same QueryBuilder is used for all routes, the databases must use the same
+// dialect, otherwise use db.RouteResolveDB.
+// If no route matches the table, an error wrapping db.ErrNoRoute is returned.
+func RouteQueryInterface(routes ...QueryRoute) QueryInterface {
+	return QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+		returnFieldNames []string, args ...any) (map[string]any, error) {
+		for _, route := range routes {
+			if route.Match(tableID) {
+				return route.QueryInterface.Query(ctx, tableID, query, returnFieldNames, args...)
+			}
+		}
+		return nil, fmt.Errorf("%w '%s'", db.ErrNoRoute, tableID.TableID())
+	})
+}
@@ -0,0 +1,217 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// DefaultSeedTable is the default name of the table where the applied seeds are recorded.
+const DefaultSeedTable = "debefix_seeds"
+
+// SeedLocker locks a seed name, so concurrent processes don't apply the same seed at the same time.
+type SeedLocker interface {
+	LockSeed(ctx context.Context, name string) (unlock func(ctx context.Context) error, err error)
+}
+
+// QueryBuilderDialectSeedLocker is an optional interface for QueryBuilderDialect, which returns the default
+// SeedLocker of the database.
+type QueryBuilderDialectSeedLocker interface {
+	SeedLocker(db DB) SeedLocker
+}
+
+// SeedResolveCallback returns the resolve callback of a seed, which must execute the queries using db, so they are
+// executed in the same transaction that records the seed.
+type SeedResolveCallback func(db DB) debefix.ResolveCallback
+
+// SeedLockerFunc is a func adapter for SeedLocker.
+type SeedLockerFunc func(ctx context.Context, name string) (unlock func(ctx context.Context) error, err error)
+
+func (f SeedLockerFunc) LockSeed(ctx context.Context, name string) (unlock func(ctx context.Context) error, err error) {
+	return f(ctx, name)
+}
+
+// Seeder applies named seeds only once, like migrations. The name and data hash of each applied seed is recorded
+// in a bookkeeping table, and a seed is only applied again if its data hash changes.
+// Each seed is locked while it is checked and applied, so concurrent processes don't apply it at the same time. If
+// the database is a TxDB, the seed is applied and recorded in a single transaction.
+// As the data is applied again on top of the existing one when it changes, seeds which are expected to change must
+// be written so they can be applied more than once.
+type Seeder struct {
+	db      DB
+	dialect QueryBuilderDialect
+	options seederOptions
+}
+
+// NewSeeder creates a Seeder which records the applied seeds in database, using dialect to build the queries.
+func NewSeeder(database DB, dialect QueryBuilderDialect, options ...SeederOption) *Seeder {
+	ret := &Seeder{
+		db:      database,
+		dialect: dialect,
+		options: seederOptions{
+			table: DefaultSeedTable,
+		},
+	}
+	for _, opt := range options {
+		opt(&ret.options)
+	}
+	return ret
+}
+
+// SeederOption is an option for NewSeeder.
+type SeederOption func(*seederOptions)
+
+// WithSeedTable sets the name of the bookkeeping table. The default is DefaultSeedTable.
+func WithSeedTable(tableName string) SeederOption {
+	return func(o *seederOptions) {
+		o.table = tableName
+	}
+}
+
+// WithSeedLocker sets the SeedLocker which is locked while checking and applying each seed. The default is the
+// locker returned by the dialect, if it implements QueryBuilderDialectSeedLocker, like the postgres, mysql and
+// sqlite dialects. Otherwise, a locker must be set.
+func WithSeedLocker(locker SeedLocker) SeederOption {
+	return func(o *seederOptions) {
+		o.locker = locker
+	}
+}
+
+type seederOptions struct {
+	table  string
+	locker SeedLocker
+}
+
+// Seed resolves the data using the callback returned by resolveCallback if the seed named name was not applied
+// yet, or if the data hash changed since it was applied. It returns whether the seed was applied.
+// The bookkeeping table is created if it doesn't exist.
+func (s *Seeder) Seed(ctx context.Context, name string, data *debefix.Data, resolveCallback SeedResolveCallback,
+	options ...debefix.ResolveOption) (applied bool, err error) {
+	hash, err := db.DataHash(data)
+	if err != nil {
+		return false, err
+	}
+
+	locker := s.options.locker
+	if locker == nil {
+		dl, ok := s.dialect.(QueryBuilderDialectSeedLocker)
+		if !ok {
+			return false, errors.New("the dialect don't have a seed locker, one must be set using WithSeedLocker")
+		}
+		locker = dl.SeedLocker(s.db)
+	}
+
+	unlock, err := locker.LockSeed(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("error locking seed '%s': %w", name, err)
+	}
+	defer func() {
+		err = errors.Join(err, unlock(context.WithoutCancel(ctx)))
+	}()
+
+	if err := s.CreateTable(ctx); err != nil {
+		return false, fmt.Errorf("error creating seed table: %w", err)
+	}
+
+	txdb, ok := s.db.(TxDB)
+	if !ok {
+		return s.apply(ctx, s.db, name, hash, data, resolveCallback, options...)
+	}
+
+	tx, err := txdb.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting seed transaction: %w", err)
+	}
+	applied, err = s.apply(ctx, tx, name, hash, data, resolveCallback, options...)
+	if err != nil {
+		return false, errors.Join(err, tx.Rollback())
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing seed '%s': %w", name, err)
+	}
+	return applied, nil
+}
+
+// apply applies the seed using database if its hash changed, and records it.
+func (s *Seeder) apply(ctx context.Context, database DB, name, hash string, data *debefix.Data,
+	resolveCallback SeedResolveCallback, options ...debefix.ResolveOption) (bool, error) {
+	appliedHash, exists, err := s.appliedHash(ctx, database, name)
+	if err != nil {
+		return false, err
+	}
+	if exists && appliedHash == hash {
+		return false, nil
+	}
+
+	if _, err := debefix.Resolve(ctx, data, resolveCallback(database), options...); err != nil {
+		return false, err
+	}
+
+	if err := s.record(ctx, database, name, hash, exists); err != nil {
+		return false, fmt.Errorf("error recording seed '%s': %w", name, err)
+	}
+	return true, nil
+}
+
+// CreateTable creates the bookkeeping table if it doesn't exist.
+func (s *Seeder) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (%s VARCHAR(255) NOT NULL PRIMARY KEY, %s VARCHAR(64) NOT NULL, %s TIMESTAMP NOT NULL)",
+		s.dialect.QuoteTable(s.options.table),
+		s.dialect.QuoteField("name"),
+		s.dialect.QuoteField("hash"),
+		s.dialect.QuoteField("applied_at"),
+	))
+	return err
+}
+
+// AppliedHash returns the data hash of the seed named name when it was applied, and whether it was applied.
+func (s *Seeder) AppliedHash(ctx context.Context, name string) (hash string, applied bool, err error) {
+	return s.appliedHash(ctx, s.db, name)
+}
+
+func (s *Seeder) appliedHash(ctx context.Context, database DB, name string) (hash string, applied bool, err error) {
+	query, args, err := BuildSelectQuery(s.dialect, debefix.TableName(s.options.table),
+		map[string]any{"name": name}, []string{"hash"})
+	if err != nil {
+		return "", false, err
+	}
+	rows, err := database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return "", false, rows.Err()
+	}
+	if err := rows.Scan(&hash); err != nil {
+		return "", false, err
+	}
+	return hash, true, rows.Err()
+}
+
+// record inserts or updates the bookkeeping row of a seed.
+func (s *Seeder) record(ctx context.Context, database DB, name, hash string, exists bool) error {
+	resolveInfo := db.ResolveDBInfo{
+		Type:    debefix.ResolveTypeAdd,
+		TableID: debefix.TableName(s.options.table),
+	}
+	if exists {
+		resolveInfo.Type = debefix.ResolveTypeUpdate
+		resolveInfo.UpdateKeyFields = []string{"name"}
+	}
+	query, args, err := BuildQuery(s.dialect, resolveInfo, map[string]any{
+		"name":       name,
+		"hash":       hash,
+		"applied_at": time.Now().UTC(),
+	}, nil)
+	if err != nil {
+		return err
+	}
+	_, err = database.ExecContext(ctx, query, args...)
+	return err
+}
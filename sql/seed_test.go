@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestSeeder(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	newData := func(name string) *debefix.Data {
+		data := debefix.NewData()
+		data.AddValues(tableTags,
+			debefix.MapValues{"tag_id": 1, "name": name},
+		)
+		return data
+	}
+
+	var locks []string
+	seeder := NewSeeder(sqldb, DefaultQueryBuilderDialect{}, WithSeedLocker(SeedLockerFunc(
+		func(ctx context.Context, name string) (func(ctx context.Context) error, error) {
+			locks = append(locks, "lock "+name)
+			return func(ctx context.Context) error {
+				locks = append(locks, "unlock "+name)
+				return nil
+			}, nil
+		})))
+	resolveFunc := func(database DB) debefix.ResolveCallback {
+		return db.ResolveFunc(ResolveDBFunc(NewSQLQueryInterface(database),
+			NewQueryBuilder(DefaultQueryBuilderDialect{})))
+	}
+
+	hash1, err := db.DataHash(newData("Go"))
+	assert.NilError(t, err)
+
+	createTable := regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS debefix_seeds (name VARCHAR(255) NOT NULL PRIMARY KEY, " +
+		"hash VARCHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL)")
+	selectHash := regexp.QuoteMeta("SELECT hash FROM debefix_seeds WHERE name = ?")
+
+	// not applied yet.
+	mock.ExpectExec(createTable).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectHash).WithArgs("tags").WillReturnRows(dbmock.NewRows("hash"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO public.tags (name, tag_id) VALUES (?, ?)")).
+		WithArgs("Go", 1).WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO debefix_seeds (applied_at, hash, name) VALUES (?, ?, ?)")).
+		WithArgs(dbmock.AnyArg(), hash1, "tags").WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	applied, err := seeder.Seed(ctx, "tags", newData("Go"), resolveFunc)
+	assert.NilError(t, err)
+	assert.Assert(t, applied)
+
+	// same hash.
+	mock.ExpectExec(createTable).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectHash).WithArgs("tags").WillReturnRows(dbmock.NewRows("hash").AddRow(hash1))
+	mock.ExpectCommit()
+
+	applied, err = seeder.Seed(ctx, "tags", newData("Go"), resolveFunc)
+	assert.NilError(t, err)
+	assert.Assert(t, !applied)
+
+	// changed hash.
+	hash2, err := db.DataHash(newData("Golang"))
+	assert.NilError(t, err)
+
+	mock.ExpectExec(createTable).WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectHash).WithArgs("tags").WillReturnRows(dbmock.NewRows("hash").AddRow(hash1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO public.tags (name, tag_id) VALUES (?, ?)")).
+		WithArgs("Golang", 1).WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE debefix_seeds SET applied_at = ?, hash = ? WHERE name = ?")).
+		WithArgs(dbmock.AnyArg(), hash2, "tags").WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	applied, err = seeder.Seed(ctx, "tags", newData("Golang"), resolveFunc)
+	assert.NilError(t, err)
+	assert.Assert(t, applied)
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.DeepEqual(t, []string{"lock tags", "unlock tags", "lock tags", "unlock tags", "lock tags", "unlock tags"},
+		locks)
+}
+
+func TestSeederRollback(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	data := debefix.NewData()
+	data.AddValues(tableTags,
+		debefix.MapValues{"tag_id": 1, "name": "Go"},
+	)
+
+	var locks []string
+	seeder := NewSeeder(sqldb, seedTestDialect{locks: &locks})
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS debefix_seeds").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM debefix_seeds WHERE name = ?")).WithArgs("tags").
+		WillReturnRows(dbmock.NewRows("hash"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO public.tags (name, tag_id) VALUES (?, ?)")).
+		WithArgs("Go", 1).WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	applied, err := seeder.Seed(ctx, "tags", data, func(database DB) debefix.ResolveCallback {
+		return ResolveFunc(NewSQLQueryInterface(database), NewQueryBuilder(DefaultQueryBuilderDialect{}))
+	})
+	assert.ErrorContains(t, err, "duplicate key")
+	assert.Assert(t, !applied)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.DeepEqual(t, []string{"lock tags", "unlock tags"}, locks)
+
+	// a locker is required.
+	_, err = NewSeeder(sqldb, DefaultQueryBuilderDialect{}).Seed(ctx, "tags", data,
+		func(database DB) debefix.ResolveCallback {
+			return ResolveFunc(NewSQLQueryInterface(database), NewQueryBuilder(DefaultQueryBuilderDialect{}))
+		})
+	assert.ErrorContains(t, err, "seed locker")
+}
+
+// seedTestDialect is a dialect which implements QueryBuilderDialectSeedLocker.
+type seedTestDialect struct {
+	DefaultQueryBuilderDialect
+	locks *[]string
+}
+
+func (d seedTestDialect) SeedLocker(db DB) SeedLocker {
+	return SeedLockerFunc(func(ctx context.Context, name string) (func(ctx context.Context) error, error) {
+		*d.locks = append(*d.locks, "lock "+name)
+		return func(ctx context.Context) error {
+			*d.locks = append(*d.locks, "unlock "+name)
+			return nil
+		}, nil
+	})
+}
@@ -0,0 +1,187 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// SlogRedacted is the value logged in place of redacted arguments.
+const SlogRedacted = "[REDACTED]"
+
+// SlogMiddleware returns a QueryInterfaceMiddleware that logs each executed query using a [slog.Logger].
+// The table ID, resolve type, duration, argument count, arguments, returned field names and error are logged as
+// attributes. The resolve type and argument field names are only available if the query was generated by
+// ResolveDBFunc.
+// If logger is nil, [slog.Default] will be used.
+func SlogMiddleware(logger *slog.Logger, options ...SlogOption) QueryInterfaceMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	optns := slogOptions{
+		level:      slog.LevelDebug,
+		errorLevel: slog.LevelError,
+		logArgs:    true,
+		redact:     map[string][]string{},
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+	return func(next QueryInterface) QueryInterface {
+		return &slogQueryInterface{logger: logger, options: optns, next: next}
+	}
+}
+
+// SlogOption is an option for SlogMiddleware.
+type SlogOption func(*slogOptions)
+
+// WithSlogLevel sets the level of successful queries. The default is [slog.LevelDebug].
+func WithSlogLevel(level slog.Level) SlogOption {
+	return func(o *slogOptions) {
+		o.level = level
+	}
+}
+
+// WithSlogErrorLevel sets the level of queries which returned an error. The default is [slog.LevelError].
+func WithSlogErrorLevel(level slog.Level) SlogOption {
+	return func(o *slogOptions) {
+		o.errorLevel = level
+	}
+}
+
+// WithSlogQuery sets whether the query text is logged. The default is false.
+func WithSlogQuery(logQuery bool) SlogOption {
+	return func(o *slogOptions) {
+		o.logQuery = logQuery
+	}
+}
+
+// WithSlogArgs sets whether the argument values are logged. The default is true.
+func WithSlogArgs(logArgs bool) SlogOption {
+	return func(o *slogOptions) {
+		o.logArgs = logArgs
+	}
+}
+
+// WithSlogRedactFields sets fields whose argument values are replaced by SlogRedacted. If tableID is nil, the
+// fields are redacted in all tables.
+// If the argument field names are not known, all argument values of the table are redacted.
+func WithSlogRedactFields(tableID debefix.TableID, fieldNames ...string) SlogOption {
+	return func(o *slogOptions) {
+		var tableName string
+		if tableID != nil {
+			tableName = tableID.TableID()
+		}
+		o.redact[tableName] = append(o.redact[tableName], fieldNames...)
+	}
+}
+
+type slogOptions struct {
+	level      slog.Level
+	errorLevel slog.Level
+	logQuery   bool
+	logArgs    bool
+	redact     map[string][]string // table name to field names, empty table name means all tables.
+}
+
+type slogQueryInterface struct {
+	logger  *slog.Logger
+	options slogOptions
+	next    QueryInterface
+}
+
+func (m *slogQueryInterface) Query(ctx context.Context, tableID debefix.TableID, query string,
+	returnFieldNames []string, args ...any) (map[string]any, error) {
+	start := time.Now()
+	ret, err := m.next.Query(ctx, tableID, query, returnFieldNames, args...)
+	duration := time.Since(start)
+
+	level := m.options.level
+	msg := "debefix query"
+	if err != nil {
+		level = m.options.errorLevel
+		msg = "debefix query error"
+	}
+	if !m.logger.Enabled(ctx, level) {
+		return ret, err
+	}
+
+	queryInfo, hasQueryInfo := QueryInfoFromContext(ctx)
+
+	attrs := []slog.Attr{
+		slog.String("table_id", tableID.TableID()),
+	}
+	if hasQueryInfo {
+		attrs = append(attrs, slog.String("resolve_type", slogResolveType(queryInfo.ResolveDBInfo.Type)))
+	}
+	attrs = append(attrs,
+		slog.Duration("duration", duration),
+		slog.Int("arg_count", len(args)),
+	)
+	if m.options.logQuery {
+		attrs = append(attrs, slog.String("query", query))
+	}
+	if m.options.logArgs && len(args) > 0 {
+		attrs = append(attrs, m.argsAttr(tableID, queryInfo.ArgFieldNames, args))
+	}
+	if len(returnFieldNames) > 0 {
+		attrs = append(attrs, slog.Any("return_field_names", returnFieldNames))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	m.logger.LogAttrs(ctx, level, msg, attrs...)
+
+	return ret, err
+}
+
+// argsAttr returns the args attribute. If the argument field names are known, it is a group keyed by field name,
+// otherwise a list of values.
+func (m *slogQueryInterface) argsAttr(tableID debefix.TableID, argFieldNames []string, args []any) slog.Attr {
+	redactFields := slices.Concat(m.options.redact[""], m.options.redact[tableID.TableID()])
+
+	if len(argFieldNames) != len(args) {
+		values := make([]any, len(args))
+		for i, arg := range args {
+			if len(redactFields) > 0 {
+				values[i] = SlogRedacted
+			} else {
+				values[i] = slogArgValue(arg)
+			}
+		}
+		return slog.Any("args", values)
+	}
+
+	var attrs []any
+	for i, arg := range args {
+		var value any = SlogRedacted
+		if !slices.Contains(redactFields, argFieldNames[i]) {
+			value = slogArgValue(arg)
+		}
+		attrs = append(attrs, slog.Any(argFieldNames[i], value))
+	}
+	return slog.Group("args", attrs...)
+}
+
+func slogArgValue(arg any) any {
+	if na, ok := arg.(sql.NamedArg); ok {
+		return na.Value
+	}
+	return arg
+}
+
+func slogResolveType(resolveType debefix.ResolveType) string {
+	switch resolveType {
+	case debefix.ResolveTypeAdd:
+		return "add"
+	case debefix.ResolveTypeUpdate:
+		return "update"
+	default:
+		return "unknown"
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"io"
 
 	"github.com/rrgmc/debefix/v2"
 )
@@ -14,26 +15,123 @@ type DB interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
+// PrepareDB is a DB which supports prepared statements, like [sql.DB] or [sql.Tx].
+type PrepareDB interface {
+	DB
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// TxDB is a DB which supports transactions, like [sql.DB].
+type TxDB interface {
+	DB
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// SessionDB returns a DB which executes all statements in the same database session, for statements which depend on
+// the session state, like session locks. If db is a connection pool, like [sql.DB], a dedicated connection is
+// taken from it, which is returned to the pool by release. Otherwise db is returned.
+func SessionDB(ctx context.Context, db DB) (session DB, release func() error, err error) {
+	pool, ok := db.(interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+	})
+	if !ok {
+		return db, func() error { return nil }, nil
+	}
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, conn.Close, nil
+}
+
 // NewSQLQueryInterface returns a QueryInterface for the passed database.
-func NewSQLQueryInterface(db DB) QueryInterface {
-	return &sqlQueryInterface{
+// The returned QueryInterface implements [io.Closer], which must be called if a statement cache is used.
+func NewSQLQueryInterface(db DB, options ...SQLQueryInterfaceOption) QueryInterface {
+	ret := &sqlQueryInterface{
 		db: db,
 	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// SQLQueryInterfaceOption is an option for NewSQLQueryInterface.
+type SQLQueryInterfaceOption func(*sqlQueryInterface)
+
+// WithStatementCache enables a cache of prepared statements keyed by the query text, so statements generated for
+// multiple rows of the same table are prepared only once. At most size statements are kept, the least recently
+// used one being closed when the limit is reached. If size is 0 there is no limit.
+// If the DB doesn't implement PrepareDB, all queries return an error.
+func WithStatementCache(size int) SQLQueryInterfaceOption {
+	return func(q *sqlQueryInterface) {
+		pdb, ok := q.db.(PrepareDB)
+		if !ok {
+			q.err = errors.New("statement cache requires a DB which implements PrepareContext")
+			return
+		}
+		q.stmtCache = newStmtCache(pdb, size)
+	}
 }
 
 type sqlQueryInterface struct {
-	db DB
+	db        DB
+	stmtCache *stmtCache
+	err       error
 }
 
 var _ QueryInterface = (*sqlQueryInterface)(nil)
+var _ io.Closer = (*sqlQueryInterface)(nil)
 
 func (q *sqlQueryInterface) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.stmtCache != nil {
+		stmt, release, err := q.stmtCache.get(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return q.query(ctx, stmt, returnFieldNames, args...)
+	}
+	return q.query(ctx, &queryDB{db: q.db, query: query}, returnFieldNames, args...)
+}
+
+// Close closes the cached prepared statements.
+func (q *sqlQueryInterface) Close() error {
+	if q.stmtCache == nil {
+		return nil
+	}
+	return q.stmtCache.close()
+}
+
+// querier is the common interface of [sql.Stmt] and a DB with a fixed query.
+type querier interface {
+	QueryContext(ctx context.Context, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, args ...any) (sql.Result, error)
+}
+
+type queryDB struct {
+	db    DB
+	query string
+}
+
+func (q *queryDB) QueryContext(ctx context.Context, args ...any) (*sql.Rows, error) {
+	return q.db.QueryContext(ctx, q.query, args...)
+}
+
+func (q *queryDB) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	return q.db.ExecContext(ctx, q.query, args...)
+}
+
+func (q *sqlQueryInterface) query(ctx context.Context, db querier, returnFieldNames []string, args ...any) (map[string]any, error) {
 	if len(returnFieldNames) == 0 {
-		_, err := q.db.ExecContext(ctx, query, args...)
+		_, err := db.ExecContext(ctx, args...)
 		return nil, err
 	}
 
-	rows, err := q.db.QueryContext(ctx, query, args...)
+	rows, err := db.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
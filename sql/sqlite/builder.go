@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+type QueryBuilderDialect struct {
+}
+
+func (d QueryBuilderDialect) QuoteTable(tableName string) string {
+	return quoteIdentifier(tableName)
+}
+
+func (d QueryBuilderDialect) QuoteField(fieldName string) string {
+	return quoteIdentifier(fieldName)
+}
+
+func (d QueryBuilderDialect) NewPlaceholderProvider() sql.QueryBuilderPlaceholderProvider {
+	return sql.DefaultQueryBuilderDialect{}.NewPlaceholderProvider()
+}
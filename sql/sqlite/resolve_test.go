@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags = debefix.TableName("public.tags")
+)
+
+func TestResolveGenerated(t *testing.T) {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("all"),
+			"tag_name": "All",
+		},
+	)
+
+	type sqlQuery struct {
+		SQL  string
+		Args []any
+	}
+
+	expectedQueryList := []sqlQuery{
+		{
+			SQL:  `INSERT INTO "public.tags" ("tag_name") VALUES (?) RETURNING "tag_id"`,
+			Args: []any{"All"},
+		},
+	}
+
+	ctx := context.Background()
+
+	var queryList []sqlQuery
+
+	_, err := debefix.Resolve(ctx, data, ResolveFunc(
+		sql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+			queryList = append(queryList, sqlQuery{
+				SQL:  query,
+				Args: args,
+			})
+			return map[string]any{"tag_id": 1}, nil
+		})))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, expectedQueryList, queryList)
+}
+
+func TestSeedLocker(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	lockQuery := regexp.QuoteMeta(`INSERT INTO "debefix_seed_locks" ("name") VALUES (?) ON CONFLICT DO NOTHING`)
+
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "debefix_seed_locks" ` +
+		`("name" VARCHAR(255) NOT NULL PRIMARY KEY)`)).
+		WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(lockQuery).WithArgs("tags").WillReturnResult(dbmock.NewResult(0, 0))
+	mock.ExpectExec(lockQuery).WithArgs("tags").WillReturnResult(dbmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "debefix_seed_locks" WHERE "name" = ?`)).WithArgs("tags").
+		WillReturnResult(dbmock.NewResult(0, 1))
+
+	unlock, err := SeedLocker(db, WithSeedLockPollInterval(time.Millisecond)).LockSeed(ctx, "tags")
+	assert.NilError(t, err)
+	assert.NilError(t, unlock(ctx))
+	assert.NilError(t, mock.ExpectationsWereMet())
+}
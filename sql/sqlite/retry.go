@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// RetryClassifier is a sql.RetryClassifier for sqlite errors. Busy (SQLITE_BUSY) and locked (SQLITE_LOCKED)
+// errors are transient errors. It supports driver errors which have a "Code() int" method, like the ones from
+// modernc.org/sqlite, and falls back to checking the error message.
+func RetryClassifier(err error) sql.RetryErrorKind {
+	var codeErr interface {
+		Code() int
+	}
+	if errors.As(err, &codeErr) {
+		// the primary result code is the least significant byte of extended result codes.
+		switch codeErr.Code() & 0xff {
+		case 5, 6: // SQLITE_BUSY, SQLITE_LOCKED
+			return sql.RetryErrorTransient
+		}
+	} else if msg := err.Error(); strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") {
+		return sql.RetryErrorTransient
+	}
+	return sql.DefaultRetryClassifier(err)
+}
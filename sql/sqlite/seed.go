@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// DefaultSeedLockTable is the default name of the table used by SeedLocker.
+const DefaultSeedLockTable = "debefix_seed_locks"
+
+// SeedLocker returns a sql.SeedLocker for sqlite, which don't have named locks. A seed is locked by inserting a row
+// in a lock table, which is created if it doesn't exist, waiting while the row exists. If a process is terminated
+// while holding a lock, its row must be deleted manually.
+func SeedLocker(db sql.DB, options ...SeedLockerOption) sql.SeedLocker {
+	optns := seedLockerOptions{
+		table:        DefaultSeedLockTable,
+		pollInterval: 100 * time.Millisecond,
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+	dialect := QueryBuilderDialect{}
+
+	return sql.SeedLockerFunc(func(ctx context.Context, name string) (func(ctx context.Context) error, error) {
+		_, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s VARCHAR(255) NOT NULL PRIMARY KEY)",
+			dialect.QuoteTable(optns.table), dialect.QuoteField("name")))
+		if err != nil {
+			return nil, fmt.Errorf("error creating seed lock table: %w", err)
+		}
+
+		lockQuery := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?) ON CONFLICT DO NOTHING",
+			dialect.QuoteTable(optns.table), dialect.QuoteField("name"))
+		for {
+			result, err := db.ExecContext(ctx, lockQuery, name)
+			if err != nil {
+				return nil, err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			if affected == 1 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(optns.pollInterval):
+			}
+		}
+
+		return func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?",
+				dialect.QuoteTable(optns.table), dialect.QuoteField("name")), name)
+			return err
+		}, nil
+	})
+}
+
+// SeedLocker returns the SeedLocker function of the package with the default options.
+func (d QueryBuilderDialect) SeedLocker(db sql.DB) sql.SeedLocker {
+	return SeedLocker(db)
+}
+
+// SeedLockerOption is an option for SeedLocker.
+type SeedLockerOption func(*seedLockerOptions)
+
+// WithSeedLockTable sets the name of the lock table. The default is DefaultSeedLockTable.
+func WithSeedLockTable(tableName string) SeedLockerOption {
+	return func(o *seedLockerOptions) {
+		o.table = tableName
+	}
+}
+
+// WithSeedLockPollInterval sets the interval between attempts to get a lock. The default is 100ms.
+func WithSeedLockPollInterval(pollInterval time.Duration) SeedLockerOption {
+	return func(o *seedLockerOptions) {
+		o.pollInterval = pollInterval
+	}
+}
+
+type seedLockerOptions struct {
+	table        string
+	pollInterval time.Duration
+}
@@ -0,0 +1,7 @@
+package sqlite
+
+import "strings"
+
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
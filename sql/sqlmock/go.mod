@@ -0,0 +1,17 @@
+module github.com/rrgmc/debefix-db/sql/sqlmock/v2
+
+go 1.23
+
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/rrgmc/debefix-db/v2 v2.1.0
+	github.com/rrgmc/debefix/v2 v2.0.6
+	gotest.tools/v3 v3.5.1
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+)
+
+replace github.com/rrgmc/debefix-db/v2 => ../..
@@ -0,0 +1,155 @@
+package sqlmock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/sqltest"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Expect resolves the data using queryBuilder, and registers the expectations of all generated queries in the
+// mock, in order. Queries with returned fields are registered with ExpectQuery, returning a row with the simulated
+// values, and the other ones with ExpectExec.
+// The values of the returned fields are generated by a sqltest.QueryInterface, so the returned values are the same
+// when the code being tested resolves the same data using the mock database.
+// As the data base time is different on each resolve, time arguments match any time value, unless
+// WithTimeTolerance is set.
+func Expect(mock sqlmock.Sqlmock, data *debefix.Data, queryBuilder dbsql.QueryBuilder, options ...Option) error {
+	optns := expectOptions{
+		quoteQuery:   true,
+		anyTime:      true,
+		rowsAffected: 1,
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	qi := sqltest.NewQueryInterface(optns.queryInterfaceOptions...)
+
+	resolved, err := debefix.Resolve(context.Background(), data, dbsql.ResolveFunc(qi, queryBuilder))
+	if err != nil {
+		return err
+	}
+
+	for _, query := range qi.Queries() {
+		expectedQuery := query.Query
+		if optns.quoteQuery {
+			expectedQuery = regexp.QuoteMeta(expectedQuery)
+		}
+
+		var args []driver.Value
+		for _, arg := range query.Args {
+			args = append(args, optns.argument(arg, resolved.BaseTime))
+		}
+
+		if len(query.ReturnFieldNames) > 0 {
+			rows := sqlmock.NewRows(query.ReturnFieldNames)
+			var values []driver.Value
+			for _, fn := range query.ReturnFieldNames {
+				values = append(values, query.Returned[fn])
+			}
+			rows.AddRow(values...)
+			mock.ExpectQuery(expectedQuery).WithArgs(args...).WillReturnRows(rows)
+		} else {
+			mock.ExpectExec(expectedQuery).WithArgs(args...).
+				WillReturnResult(sqlmock.NewResult(0, optns.rowsAffected))
+		}
+	}
+
+	return nil
+}
+
+// Option is an option for Expect.
+type Option func(*expectOptions)
+
+// WithQuoteQuery sets whether the queries are quoted using [regexp.QuoteMeta]. It is true by default, and
+// should be set to false if the mock uses [sqlmock.QueryMatcherEqual].
+func WithQuoteQuery(quoteQuery bool) Option {
+	return func(o *expectOptions) {
+		o.quoteQuery = quoteQuery
+	}
+}
+
+// WithTimeTolerance matches time arguments with the same offset from the base time of the resolve, with the passed
+// tolerance. The tolerance must account for the difference between the base times of the resolve done by Expect and
+// the one done by the code being tested.
+func WithTimeTolerance(tolerance time.Duration) Option {
+	return func(o *expectOptions) {
+		o.anyTime = false
+		o.timeTolerance = tolerance
+	}
+}
+
+// WithRowsAffected sets the rows affected returned by the ExpectExec expectations. The default is 1.
+func WithRowsAffected(rowsAffected int64) Option {
+	return func(o *expectOptions) {
+		o.rowsAffected = rowsAffected
+	}
+}
+
+// WithQueryInterfaceOptions sets the options of the sqltest.QueryInterface used to simulate the returned fields.
+func WithQueryInterfaceOptions(options ...sqltest.Option) Option {
+	return func(o *expectOptions) {
+		o.queryInterfaceOptions = append(o.queryInterfaceOptions, options...)
+	}
+}
+
+type expectOptions struct {
+	quoteQuery            bool
+	anyTime               bool
+	timeTolerance         time.Duration
+	rowsAffected          int64
+	queryInterfaceOptions []sqltest.Option
+}
+
+// argument returns the expected argument for a query argument.
+func (o expectOptions) argument(arg any, baseTime time.Time) driver.Value {
+	switch v := arg.(type) {
+	case sql.NamedArg:
+		value := o.argument(v.Value, baseTime)
+		if _, ok := value.(sqlmock.Argument); ok {
+			// sqlmock don't support matchers in named arguments.
+			return value
+		}
+		return sql.Named(v.Name, value)
+	case time.Time:
+		return timeArgument{
+			anyTime:   o.anyTime,
+			offset:    v.Sub(baseTime),
+			tolerance: o.timeTolerance,
+		}
+	default:
+		return arg
+	}
+}
+
+// timeArgument matches time arguments.
+type timeArgument struct {
+	anyTime   bool
+	offset    time.Duration // offset from the base time of the resolve.
+	tolerance time.Duration
+}
+
+var _ sqlmock.Argument = timeArgument{}
+
+func (a timeArgument) Match(value driver.Value) bool {
+	t, ok := value.(time.Time)
+	if !ok {
+		return false
+	}
+	if a.anyTime {
+		return true
+	}
+	// the base time of the resolve being tested is near the current time.
+	d := time.Now().Add(a.offset).Sub(t)
+	if d < 0 {
+		d = -d
+	}
+	return d <= a.tolerance
+}
@@ -0,0 +1,94 @@
+package sqlmock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags  = debefix.TableName("public.tags")
+	tablePosts = debefix.TableName("public.posts")
+)
+
+func TestExpect(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options []Option
+	}{
+		{
+			name: "any time",
+		},
+		{
+			name:    "time tolerance",
+			options: []Option{WithTimeTolerance(time.Minute)},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			db, mock, err := sqlmock.New()
+			assert.NilError(t, err)
+			defer db.Close()
+
+			data := testData()
+
+			err = Expect(mock, data, postgres.QueryBuilder(), test.options...)
+			assert.NilError(t, err)
+
+			_, err = debefix.Resolve(ctx, data, postgres.ResolveFunc(sql.NewSQLQueryInterface(db)))
+			assert.NilError(t, err)
+
+			assert.NilError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExpectMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	assert.NilError(t, err)
+	defer db.Close()
+
+	err = Expect(mock, testData(), postgres.QueryBuilder())
+	assert.NilError(t, err)
+
+	data := testData()
+	data.AddValues(tablePosts, debefix.MapValues{
+		"post_id": 2,
+		"title":   "Unexpected post",
+	})
+
+	_, err = debefix.Resolve(ctx, data, postgres.ResolveFunc(sql.NewSQLQueryInterface(db)))
+	assert.ErrorContains(t, err, "all expectations were already fulfilled")
+}
+
+func testData() *debefix.Data {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":     debefix.ResolveValueResolve(),
+			"_refid":     debefix.SetValueRefID("all"),
+			"tag_name":   "All",
+			"created_at": debefix.ValueBaseTimeAdd(debefix.WithAddHours(-1)),
+		},
+	)
+
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "all", "tag_id"),
+			"title":   "First post",
+		},
+	)
+
+	return data
+}
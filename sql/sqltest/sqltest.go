@@ -0,0 +1,166 @@
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+// Query is a query executed by QueryInterface.
+type Query struct {
+	TableID          string
+	Query            string
+	Args             []any
+	ReturnFieldNames []string
+	Returned         map[string]any
+}
+
+// QueryInterface is a deterministic simulated sql.QueryInterface which records all executed queries.
+// The returned field values are sequential UUIDs, unless WithReturnFunc is set.
+type QueryInterface struct {
+	returnFunc func(tableID debefix.TableID, fieldName string, seq int) any
+
+	mu      sync.Mutex
+	seq     int
+	queries []Query
+}
+
+var _ dbsql.QueryInterface = (*QueryInterface)(nil)
+
+// NewQueryInterface creates a QueryInterface.
+func NewQueryInterface(options ...Option) *QueryInterface {
+	ret := &QueryInterface{
+		returnFunc: func(tableID debefix.TableID, fieldName string, seq int) any {
+			var u uuid.UUID
+			binary.BigEndian.PutUint64(u[8:], uint64(seq))
+			return u
+		},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// Option is an option for NewQueryInterface.
+type Option func(q *QueryInterface)
+
+// WithReturnFunc sets a function to generate the values of the returned fields. seq is a sequence number starting
+// from 1, incremented for each returned field.
+func WithReturnFunc(returnFunc func(tableID debefix.TableID, fieldName string, seq int) any) Option {
+	return func(q *QueryInterface) {
+		q.returnFunc = returnFunc
+	}
+}
+
+func (q *QueryInterface) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string,
+	args ...any) (map[string]any, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	returnFieldNames = slices.Sorted(slices.Values(returnFieldNames))
+
+	var ret map[string]any
+	if len(returnFieldNames) > 0 {
+		ret = map[string]any{}
+		for _, fn := range returnFieldNames {
+			q.seq++
+			ret[fn] = q.returnFunc(tableID, fn, q.seq)
+		}
+	}
+
+	q.queries = append(q.queries, Query{
+		TableID:          tableID.TableID(),
+		Query:            query,
+		Args:             args,
+		ReturnFieldNames: returnFieldNames,
+		Returned:         ret,
+	})
+
+	return ret, nil
+}
+
+// Queries returns the recorded queries.
+func (q *QueryInterface) Queries() []Query {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.queries)
+}
+
+// Format returns a text representation of the queries. Time arguments are formatted relative to baseTime, if it is
+// not zero, so the output is stable for data using [debefix.ValueBaseTimeAdd].
+func Format(queries []Query, baseTime time.Time) string {
+	var b strings.Builder
+	for i, query := range queries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		_, _ = fmt.Fprintf(&b, "-- %d: %s\n", i+1, query.TableID)
+		_, _ = fmt.Fprintf(&b, "%s\n", query.Query)
+		for argIdx, arg := range query.Args {
+			_, _ = fmt.Fprintf(&b, "-- arg %d: %s\n", argIdx+1, formatValue(arg, baseTime))
+		}
+		for _, fn := range query.ReturnFieldNames {
+			_, _ = fmt.Fprintf(&b, "-- returned %s: %s\n", fn, formatValue(query.Returned[fn], baseTime))
+		}
+	}
+	return b.String()
+}
+
+// AssertGolden resolves the data using queryBuilder and a QueryInterface, and compares the formatted queries with
+// the golden file "testdata/<filename>". Run the tests with the "-update" flag to rewrite the golden file.
+func AssertGolden(t testing.TB, data *debefix.Data, queryBuilder dbsql.QueryBuilder, filename string,
+	options ...debefix.ResolveOption) {
+	t.Helper()
+
+	qi := NewQueryInterface()
+	resolved, err := debefix.Resolve(context.Background(), data, dbsql.ResolveFunc(qi, queryBuilder), options...)
+	assert.NilError(t, err)
+
+	golden.Assert(t, Format(qi.Queries(), resolved.BaseTime), filename)
+}
+
+func formatValue(value any, baseTime time.Time) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case sql.NamedArg:
+		return fmt.Sprintf("@%s=%s", v.Name, formatValue(v.Value, baseTime))
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%q", v)
+	case time.Time:
+		if baseTime.IsZero() {
+			return v.Format(time.RFC3339Nano)
+		}
+		d := v.Sub(baseTime)
+		switch {
+		case d == 0:
+			return "basetime"
+		case d > 0:
+			return "basetime+" + d.String()
+		default:
+			return "basetime" + d.String()
+		}
+	case *time.Time:
+		if v == nil {
+			return "NULL"
+		}
+		return formatValue(*v, baseTime)
+	default:
+		return fmt.Sprintf("%v (%T)", v, v)
+	}
+}
@@ -0,0 +1,65 @@
+package sqltest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags  = debefix.TableName("tags")
+	tablePosts = debefix.TableName("posts")
+)
+
+func TestAssertGolden(t *testing.T) {
+	AssertGolden(t, testData(), sql.NewQueryBuilder(sql.DefaultQueryBuilderDialect{}), "resolve.golden")
+}
+
+func TestFormat(t *testing.T) {
+	baseTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	qi := NewQueryInterface(WithReturnFunc(func(tableID debefix.TableID, fieldName string, seq int) any {
+		return seq * 10
+	}))
+
+	ret, err := qi.Query(context.Background(), tableTags, "INSERT INTO tags (name, created_at) VALUES (?, ?)",
+		[]string{"tag_id"}, "All", baseTime.Add(-time.Hour))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]any{"tag_id": 10}, ret)
+
+	assert.Equal(t, `-- 1: tags
+INSERT INTO tags (name, created_at) VALUES (?, ?)
+-- arg 1: "All"
+-- arg 2: basetime-1h0m0s
+-- returned tag_id: 10 (int)
+`, Format(qi.Queries(), baseTime))
+}
+
+func testData() *debefix.Data {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":     debefix.ResolveValueResolve(),
+			"_refid":     debefix.SetValueRefID("all"),
+			"name":       "All",
+			"created_at": debefix.ValueBaseTimeAdd(debefix.WithAddHours(-2)),
+		},
+	)
+
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id":    1,
+			"tag_id":     debefix.ValueRefID(tableTags, "all", "tag_id"),
+			"title":      "First post",
+			"deleted_at": nil,
+			"created_at": debefix.ValueBaseTimeAdd(),
+		},
+	)
+
+	return data
+}
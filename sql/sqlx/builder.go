@@ -0,0 +1,106 @@
+package sqlx
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/postgres"
+)
+
+// QueryBuilderDialect returns a QueryBuilderDialect for the database driver, as returned by [sqlx.DB.DriverName].
+// The placeholders are detected using [sqlx.BindType], and the identifier quoting as described in WithQuoteDialect.
+func QueryBuilderDialect(db DB, options ...Option) (dbsql.QueryBuilderDialect, error) {
+	return newQueryBuilderDialect(db.DriverName(), false, options...)
+}
+
+// NamedQueryBuilderDialect returns a QueryBuilderDialect for the database driver which generates named placeholders
+// (:arg1, :arg2), whose arguments are bound by the QueryInterface returned by NewQueryInterface using
+// [sqlx.DB.BindNamed].
+func NamedQueryBuilderDialect(db DB, options ...Option) (dbsql.QueryBuilderDialect, error) {
+	return newQueryBuilderDialect(db.DriverName(), true, options...)
+}
+
+// Option is an option for the query builder dialect.
+type Option func(o *dialectOptions)
+
+// WithQuoteDialect sets the dialect used to quote table and field names, the placeholders are still the ones of the
+// driver bind type.
+// If not set, the [sqlx.BindType] of the driver is used: [sqlx.DOLLAR] drivers use the postgres quoting, and the
+// others, like the [sqlx.QUESTION] ones which are shared by MySQL and SQLite, don't quote names.
+func WithQuoteDialect(dialect dbsql.QueryBuilderDialect) Option {
+	return func(o *dialectOptions) {
+		o.quoteDialect = dialect
+	}
+}
+
+type dialectOptions struct {
+	quoteDialect dbsql.QueryBuilderDialect
+}
+
+func newQueryBuilderDialect(driverName string, named bool, options ...Option) (dbsql.QueryBuilderDialect, error) {
+	bindType := sqlx.BindType(driverName)
+	if bindType == sqlx.UNKNOWN {
+		return nil, fmt.Errorf("unknown bind type for driver '%s'", driverName)
+	}
+	var optns dialectOptions
+	for _, opt := range options {
+		opt(&optns)
+	}
+	if optns.quoteDialect == nil {
+		optns.quoteDialect = bindTypeQuoteDialect(bindType)
+	}
+	return &queryBuilderDialect{
+		QueryBuilderDialect: optns.quoteDialect,
+		bindType:            bindType,
+		named:               named,
+	}, nil
+}
+
+// bindTypeQuoteDialect returns the dialect used to quote names for the bind type.
+func bindTypeQuoteDialect(bindType int) dbsql.QueryBuilderDialect {
+	switch bindType {
+	case sqlx.DOLLAR:
+		return postgres.QueryBuilderDialect{}
+	default:
+		return dbsql.DefaultQueryBuilderDialect{}
+	}
+}
+
+type queryBuilderDialect struct {
+	dbsql.QueryBuilderDialect
+	bindType int
+	named    bool
+}
+
+func (d *queryBuilderDialect) NewPlaceholderProvider() dbsql.QueryBuilderPlaceholderProvider {
+	return &queryBuilderDialectPlaceholderProvider{
+		bindType: d.bindType,
+		named:    d.named,
+	}
+}
+
+// queryBuilderDialectPlaceholderProvider generates placeholders for a sqlx bind type.
+type queryBuilderDialectPlaceholderProvider struct {
+	bindType int
+	named    bool
+	c        int
+}
+
+func (p *queryBuilderDialectPlaceholderProvider) Next() (placeholder string, argName string) {
+	p.c++
+	if p.named {
+		argName = fmt.Sprintf("arg%d", p.c)
+		return ":" + argName, argName
+	}
+	switch p.bindType {
+	case sqlx.DOLLAR:
+		return fmt.Sprintf("$%d", p.c), ""
+	case sqlx.NAMED:
+		return fmt.Sprintf(":arg%d", p.c), ""
+	case sqlx.AT:
+		return fmt.Sprintf("@p%d", p.c), ""
+	default:
+		return "?", ""
+	}
+}
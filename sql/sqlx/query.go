@@ -0,0 +1,23 @@
+package sqlx
+
+import (
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+)
+
+// QueryBuilder returns a sql.QueryBuilder for the database driver.
+func QueryBuilder(db DB, options ...Option) (dbsql.QueryBuilder, error) {
+	dialect, err := QueryBuilderDialect(db, options...)
+	if err != nil {
+		return nil, err
+	}
+	return dbsql.NewQueryBuilder(dialect), nil
+}
+
+// NamedQueryBuilder returns a sql.QueryBuilder for the database driver using named arguments.
+func NamedQueryBuilder(db DB, options ...Option) (dbsql.QueryBuilder, error) {
+	dialect, err := NamedQueryBuilderDialect(db, options...)
+	if err != nil {
+		return nil, err
+	}
+	return dbsql.NewQueryBuilder(dialect), nil
+}
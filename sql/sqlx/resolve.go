@@ -0,0 +1,27 @@
+package sqlx
+
+import (
+	"github.com/rrgmc/debefix-db/v2"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// ResolveDBFunc is a db.ResolveDBCallback helper to generate records using a sqlx database, detecting the query
+// builder dialect from its driver.
+func ResolveDBFunc(sdb DB, options ...Option) (db.ResolveDBCallback, error) {
+	queryBuilder, err := QueryBuilder(sdb, options...)
+	if err != nil {
+		return nil, err
+	}
+	return dbsql.ResolveDBFunc(NewQueryInterface(sdb), queryBuilder), nil
+}
+
+// ResolveFunc is a debefix.ResolveCallback helper to generate records using a sqlx database, detecting the query
+// builder dialect from its driver.
+func ResolveFunc(sdb DB, options ...Option) (debefix.ResolveCallback, error) {
+	callback, err := ResolveDBFunc(sdb, options...)
+	if err != nil {
+		return nil, err
+	}
+	return db.ResolveFunc(callback), nil
+}
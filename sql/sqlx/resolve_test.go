@@ -0,0 +1,129 @@
+package sqlx
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/jmoiron/sqlx"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix-db/v2/sql/mysql"
+	"github.com/rrgmc/debefix-db/v2/sql/sqlite"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	tableTags     = debefix.TableName("tags")
+	tablePostTags = debefix.TableName("post_tags")
+)
+
+func init() {
+	sqlx.BindDriver("sqlite", sqlx.QUESTION)
+}
+
+func openDB(t *testing.T) *sqlx.DB {
+	sdb, err := sqlx.Open("sqlite", ":memory:")
+	assert.NilError(t, err)
+	sdb.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sdb.Close() })
+
+	sdb.MustExec(`CREATE TABLE tags (tag_id INTEGER PRIMARY KEY AUTOINCREMENT, tag_name TEXT NOT NULL)`)
+	sdb.MustExec(`CREATE TABLE post_tags (post_id INTEGER NOT NULL, tag_id INTEGER NOT NULL)`)
+	return sdb
+}
+
+func testData() *debefix.Data {
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("all"),
+			"tag_name": "All",
+		},
+		debefix.MapValues{
+			"tag_id":   debefix.ResolveValueResolve(),
+			"_refid":   debefix.SetValueRefID("half"),
+			"tag_name": "Half",
+		},
+	)
+
+	data.AddValues(tablePostTags,
+		debefix.MapValues{
+			"post_id": 1,
+			"tag_id":  debefix.ValueRefID(tableTags, "half", "tag_id"),
+		},
+	)
+
+	return data
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	sdb := openDB(t)
+
+	resolveFunc, err := ResolveFunc(sdb)
+	assert.NilError(t, err)
+
+	_, err = debefix.Resolve(ctx, testData(), resolveFunc)
+	assert.NilError(t, err)
+
+	var tagIDs []int64
+	assert.NilError(t, sdb.Select(&tagIDs, `SELECT tag_id FROM post_tags`))
+	assert.DeepEqual(t, []int64{2}, tagIDs)
+}
+
+func TestResolveNamed(t *testing.T) {
+	ctx := context.Background()
+	sdb := openDB(t)
+
+	queryBuilder, err := NamedQueryBuilder(sdb, WithQuoteDialect(sqlite.QueryBuilderDialect{}))
+	assert.NilError(t, err)
+
+	var queries []string
+
+	_, err = debefix.Resolve(ctx, testData(), dbsql.ResolveFunc(
+		dbsql.QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+			queries = append(queries, query)
+			return NewQueryInterface(sdb).Query(ctx, tableID, query, returnFieldNames, args...)
+		}), queryBuilder))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{
+		`INSERT INTO "tags" ("tag_name") VALUES (:arg1) RETURNING "tag_id"`,
+		`INSERT INTO "tags" ("tag_name") VALUES (:arg1) RETURNING "tag_id"`,
+		`INSERT INTO "post_tags" ("post_id", "tag_id") VALUES (:arg1, :arg2)`,
+	}, queries)
+
+	var tagIDs []int64
+	assert.NilError(t, sdb.Select(&tagIDs, `SELECT tag_id FROM post_tags`))
+	assert.DeepEqual(t, []int64{2}, tagIDs)
+}
+
+func TestQueryBuilderDialect(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		driverName  string
+		options     []Option
+		table       string
+		placeholder string
+	}{
+		{"postgres", "postgres", nil, `"public.tags"`, "$1"},
+		{"mysql", "mysql", nil, "public.tags", "?"},
+		{"mysql quote", "mysql", []Option{WithQuoteDialect(mysql.QueryBuilderDialect{})}, "`public.tags`", "?"},
+		{"sqlserver", "sqlserver", nil, "public.tags", "@p1"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dialect, err := newQueryBuilderDialect(test.driverName, false, test.options...)
+			assert.NilError(t, err)
+			assert.Equal(t, test.table, dialect.QuoteTable("public.tags"))
+			placeholder, argName := dialect.NewPlaceholderProvider().Next()
+			assert.Equal(t, test.placeholder, placeholder)
+			assert.Equal(t, "", argName)
+		})
+	}
+
+	_, err := newQueryBuilderDialect("unknown", false)
+	assert.ErrorContains(t, err, "unknown bind type")
+}
@@ -0,0 +1,59 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	dbsql "github.com/rrgmc/debefix-db/v2/sql"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// DB is an abstraction over [sqlx.DB] or [sqlx.Tx].
+type DB interface {
+	sqlx.ExtContext
+}
+
+// NewQueryInterface returns a QueryInterface for the passed sqlx database.
+// If all the query arguments are [sql.NamedArg], like the ones generated by NamedQueryBuilderDialect, the query is
+// bound using sqlx named parameters support.
+func NewQueryInterface(db DB) dbsql.QueryInterface {
+	return &queryInterface{
+		db: db,
+		qi: dbsql.NewSQLQueryInterface(db),
+	}
+}
+
+type queryInterface struct {
+	db DB
+	qi dbsql.QueryInterface
+}
+
+var _ dbsql.QueryInterface = (*queryInterface)(nil)
+
+func (q *queryInterface) Query(ctx context.Context, tableID debefix.TableID, query string, returnFieldNames []string, args ...any) (map[string]any, error) {
+	if namedArgs, ok := namedArgsMap(args); ok {
+		var err error
+		query, args, err = q.db.BindNamed(query, namedArgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return q.qi.Query(ctx, tableID, query, returnFieldNames, args...)
+}
+
+// namedArgsMap returns the arguments as a map if all of them are [sql.NamedArg].
+func namedArgsMap(args []any) (map[string]any, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	ret := map[string]any{}
+	for _, arg := range args {
+		na, ok := arg.(sql.NamedArg)
+		if !ok {
+			return nil, false
+		}
+		ret[na.Name] = na.Value
+	}
+	return ret, true
+}
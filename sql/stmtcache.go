@@ -0,0 +1,182 @@
+package sql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// CloseProcess returns a [debefix.Process] which closes the QueryInterface when the resolve finishes, if it
+// implements [io.Closer]. As processes are not finished when the resolve fails, the QueryInterface should also be
+// closed by the caller in that case, or ResolveClose used instead.
+func CloseProcess(qi QueryInterface) debefix.Process {
+	return &closeProcess{qi: qi}
+}
+
+// ResolveClose calls [debefix.Resolve] and closes the QueryInterface when it finishes, if it implements
+// [io.Closer], including when the resolve fails.
+func ResolveClose(ctx context.Context, data *debefix.Data, qi QueryInterface, resolveCallback debefix.ResolveCallback,
+	options ...debefix.ResolveOption) (*debefix.ResolvedData, error) {
+	resolved, err := debefix.Resolve(ctx, data, resolveCallback,
+		append(slices.Clone(options), debefix.WithResolveOptionProcess(CloseProcess(qi)))...)
+	if err != nil {
+		if closer, ok := qi.(io.Closer); ok {
+			err = errors.Join(err, closer.Close())
+		}
+		return nil, err
+	}
+	return resolved, nil
+}
+
+type closeProcess struct {
+	qi QueryInterface
+}
+
+func (p *closeProcess) Start(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+func (p *closeProcess) Finish(ctx context.Context) error {
+	if closer, ok := p.qi.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// stmtCache is a LRU cache of prepared statements. Evicted statements are only closed after all their users
+// released them.
+type stmtCache struct {
+	db   PrepareDB
+	size int
+
+	mu      sync.Mutex
+	lru     *list.List // of *stmtCacheEntry, most recently used first.
+	entries map[string]*list.Element
+	closed  bool
+}
+
+type stmtCacheEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db PrepareDB, size int) *stmtCache {
+	return &stmtCache{
+		db:      db,
+		size:    size,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get returns the prepared statement for the query, preparing it if not cached. release must be called after
+// the statement is used.
+func (c *stmtCache) get(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	if stmt, release, ok, err := c.cached(query); ok || err != nil {
+		return stmt, release, err
+	}
+
+	// prepare outside the lock, so other queries are not blocked by the database round trip.
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		_ = stmt.Close()
+		return nil, nil, errors.New("statement cache is closed")
+	}
+
+	if elem, ok := c.entries[query]; ok {
+		// prepared concurrently by another query.
+		_ = stmt.Close()
+		return c.use(elem)
+	}
+
+	entry := &stmtCacheEntry{query: query, stmt: stmt, refs: 1}
+	c.entries[query] = c.lru.PushFront(entry)
+
+	for c.size > 0 && c.lru.Len() > c.size {
+		// errors closing evicted statements don't affect the current query.
+		_ = c.evict(c.lru.Back())
+	}
+
+	return entry.stmt, c.releaseFunc(entry), nil
+}
+
+// cached returns the cached statement for the query, if available.
+func (c *stmtCache) cached(query string) (*sql.Stmt, func(), bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, nil, false, errors.New("statement cache is closed")
+	}
+	elem, ok := c.entries[query]
+	if !ok {
+		return nil, nil, false, nil
+	}
+	stmt, release, err := c.use(elem)
+	return stmt, release, true, err
+}
+
+// use marks the entry as the most recently used and acquires it. Must be called with the lock held.
+func (c *stmtCache) use(elem *list.Element) (*sql.Stmt, func(), error) {
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*stmtCacheEntry)
+	entry.refs++
+	return entry.stmt, c.releaseFunc(entry), nil
+}
+
+func (c *stmtCache) releaseFunc(entry *stmtCacheEntry) func() {
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entry.refs--
+		if entry.evicted && entry.refs == 0 {
+			_ = entry.stmt.Close()
+		}
+	}
+}
+
+// evict removes the entry from the cache, closing the statement if it is not being used.
+func (c *stmtCache) evict(elem *list.Element) error {
+	entry := c.lru.Remove(elem).(*stmtCacheEntry)
+	delete(c.entries, entry.query)
+	entry.evicted = true
+	if entry.refs == 0 {
+		return entry.stmt.Close()
+	}
+	return nil
+}
+
+// close closes all cached statements.
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	var err error
+	for c.lru.Len() > 0 {
+		err = errors.Join(err, c.evict(c.lru.Front()))
+	}
+	return err
+}
+
+// len returns the number of cached statements.
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
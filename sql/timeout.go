@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rrgmc/debefix/v2"
+)
+
+// TimeoutDiagnostics returns diagnostic information about a statement which timed out, like the sessions blocking
+// it. It is called before the statement is canceled, so the database state can still be inspected.
+type TimeoutDiagnostics func(ctx context.Context, tableID debefix.TableID, query string) (string, error)
+
+// TimeoutError is returned by TimeoutMiddleware when a statement times out. It wraps the query error and
+// [context.DeadlineExceeded].
+type TimeoutError struct {
+	TableID     debefix.TableID
+	Query       string
+	Timeout     time.Duration
+	Diagnostics string // the TimeoutDiagnostics output, if set.
+	Err         error
+}
+
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("statement on table '%s' timed out after %s: `%s`", e.TableID.TableID(), e.Timeout, e.Query)
+	if e.Diagnostics != "" {
+		msg += "\n" + e.Diagnostics
+	}
+	return msg
+}
+
+func (e *TimeoutError) Unwrap() []error {
+	return []error{e.Err, context.DeadlineExceeded}
+}
+
+var errStatementTimeout = errors.New("statement timeout")
+
+// TimeoutMiddleware returns a QueryInterfaceMiddleware which cancels statements that take longer than timeout,
+// returning a *TimeoutError.
+func TimeoutMiddleware(timeout time.Duration, options ...TimeoutOption) QueryInterfaceMiddleware {
+	var optns timeoutOptions
+	for _, opt := range options {
+		opt(&optns)
+	}
+	return func(next QueryInterface) QueryInterface {
+		return QueryInterfaceFunc(func(ctx context.Context, tableID debefix.TableID, query string,
+			returnFieldNames []string, args ...any) (map[string]any, error) {
+			queryCtx, cancel := context.WithCancelCause(ctx)
+			defer cancel(nil)
+
+			var diagnostics string
+			var diagnosticsErr error
+			done := make(chan struct{})
+			timer := time.AfterFunc(timeout, func() {
+				defer close(done)
+				if optns.diagnostics != nil {
+					// the diagnostics must not use the statement context, which will be canceled.
+					dctx, dcancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+					diagnostics, diagnosticsErr = optns.diagnostics(dctx, tableID, query)
+					dcancel()
+				}
+				cancel(errStatementTimeout)
+			})
+
+			ret, err := next.Query(queryCtx, tableID, query, returnFieldNames, args...)
+			if timer.Stop() {
+				return ret, err
+			}
+			<-done
+			if err == nil || !errors.Is(context.Cause(queryCtx), errStatementTimeout) {
+				return ret, err
+			}
+
+			if diagnosticsErr != nil {
+				diagnostics = fmt.Sprintf("error getting timeout diagnostics: %s", diagnosticsErr)
+			}
+			return nil, &TimeoutError{
+				TableID:     tableID,
+				Query:       query,
+				Timeout:     timeout,
+				Diagnostics: diagnostics,
+				Err:         err,
+			}
+		})
+	}
+}
+
+// TimeoutOption is an option for TimeoutMiddleware.
+type TimeoutOption func(*timeoutOptions)
+
+// WithTimeoutDiagnostics sets a function to get diagnostic information when a statement times out.
+func WithTimeoutDiagnostics(diagnostics TimeoutDiagnostics) TimeoutOption {
+	return func(o *timeoutOptions) {
+		o.diagnostics = diagnostics
+	}
+}
+
+type timeoutOptions struct {
+	diagnostics TimeoutDiagnostics
+}
@@ -0,0 +1,456 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2"
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Verifier is used to verify that the database contains the rows of a fixture, instead of inserting them.
+// Use Verifier.ResolveFunc as the [debefix.Resolve] callback, and check Verifier.Err after it returns.
+type Verifier struct {
+	db            DB
+	dialect       QueryBuilderDialect
+	keyFields     map[string][]string // table ID: key field names
+	ignoreFields  map[string][]string // table ID ("" for all tables): ignored field names
+	timeTolerance time.Duration
+
+	mu    sync.Mutex
+	diffs []VerifyDiff
+}
+
+// VerifyDiff is a difference between a fixture row and the database.
+type VerifyDiff struct {
+	TableID debefix.TableID
+	Key     map[string]any // the fields used to find the row.
+	Missing bool           // the row was not found.
+	Fields  []VerifyFieldDiff
+}
+
+// VerifyFieldDiff is a field value difference between a fixture row and the database.
+type VerifyFieldDiff struct {
+	FieldName string
+	Expected  any
+	Actual    any
+}
+
+// NewVerifier creates a Verifier for the database.
+func NewVerifier(db DB, dialect QueryBuilderDialect, options ...VerifyOption) *Verifier {
+	ret := &Verifier{
+		db:           db,
+		dialect:      dialect,
+		keyFields:    map[string][]string{},
+		ignoreFields: map[string][]string{},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+// VerifyOption is an option for NewVerifier.
+type VerifyOption func(v *Verifier)
+
+// WithVerifyKeyFields sets the fields used to find the rows of a table. If not set, or if any of the fields is not
+// present in the row, all the row fields are used, and if no row has all of them, the differences are reported
+// against the table row with the most equal fields, if at least half of them are equal. As this reads all the
+// table rows, key fields should be set for large tables.
+func WithVerifyKeyFields(tableID debefix.TableID, fieldNames ...string) VerifyOption {
+	return func(v *Verifier) {
+		v.keyFields[tableID.TableID()] = fieldNames
+	}
+}
+
+// WithVerifyIgnoreFields sets fields to ignore when comparing the rows of a table. If tableID is nil, the fields
+// are ignored in all tables.
+func WithVerifyIgnoreFields(tableID debefix.TableID, fieldNames ...string) VerifyOption {
+	return func(v *Verifier) {
+		key := ""
+		if tableID != nil {
+			key = tableID.TableID()
+		}
+		v.ignoreFields[key] = append(v.ignoreFields[key], fieldNames...)
+	}
+}
+
+// WithVerifyTimeTolerance sets the maximum difference for time values to be considered equal, for columns like
+// "updated_at". Time fields are not used to find rows if a tolerance is set.
+func WithVerifyTimeTolerance(tolerance time.Duration) VerifyOption {
+	return func(v *Verifier) {
+		v.timeTolerance = tolerance
+	}
+}
+
+// ResolveDBFunc returns a db.ResolveDBCallback which selects and compares the rows instead of inserting them.
+// The returned fields are read from the found rows, so they can be referenced by other rows.
+func (v *Verifier) ResolveDBFunc() db.ResolveDBCallback {
+	return v.resolve
+}
+
+// ResolveFunc returns a debefix.ResolveCallback which selects and compares the rows instead of inserting them.
+func (v *Verifier) ResolveFunc() debefix.ResolveCallback {
+	return db.ResolveFunc(v.ResolveDBFunc())
+}
+
+// Diffs returns the differences found.
+func (v *Verifier) Diffs() []VerifyDiff {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return slices.Clone(v.diffs)
+}
+
+// Err returns an error describing all the differences found, or nil if there were no differences.
+func (v *Verifier) Err() error {
+	diffs := v.Diffs()
+	if len(diffs) == 0 {
+		return nil
+	}
+	var errs []error
+	for _, diff := range diffs {
+		errs = append(errs, errors.New(diff.String()))
+	}
+	return fmt.Errorf("fixture verification failed: %w", errors.Join(errs...))
+}
+
+// String returns the description of the difference.
+func (d VerifyDiff) String() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "table '%s' row {", d.TableID.TableID())
+	for i, fn := range slices.Sorted(maps.Keys(d.Key)) {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		_, _ = fmt.Fprintf(&b, "%s: %v", fn, d.Key[fn])
+	}
+	b.WriteString("}: ")
+	if d.Missing {
+		b.WriteString("row not found")
+		return b.String()
+	}
+	for i, f := range d.Fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		_, _ = fmt.Fprintf(&b, "field '%s' expected '%v' got '%v'", f.FieldName, f.Expected, f.Actual)
+	}
+	return b.String()
+}
+
+func (v *Verifier) resolve(ctx context.Context, resolveInfo db.ResolveDBInfo, fields map[string]any,
+	returnFields map[string]debefix.ResolveValue) (map[string]any, error) {
+	tableID := resolveInfo.TableID
+
+	var compareFieldNames []string
+	for fn := range fields {
+		if !v.isIgnored(tableID, fn) {
+			compareFieldNames = append(compareFieldNames, fn)
+		}
+	}
+
+	keyFields := map[string]any{}
+	allFieldsKey := false
+	if resolveInfo.Type == debefix.ResolveTypeUpdate {
+		for _, fn := range resolveInfo.UpdateKeyFields {
+			keyFields[fn] = fields[fn]
+		}
+	} else if keyFieldNames, ok := v.keyFields[tableID.TableID()]; ok && hasAllFields(fields, keyFieldNames) {
+		for _, fn := range keyFieldNames {
+			keyFields[fn] = fields[fn]
+		}
+	} else {
+		allFieldsKey = true
+		for _, fn := range compareFieldNames {
+			if _, isTime := fields[fn].(time.Time); isTime && v.timeTolerance > 0 {
+				continue
+			}
+			keyFields[fn] = fields[fn]
+		}
+	}
+
+	selectFieldNames := slices.Concat(compareFieldNames, slices.Collect(maps.Keys(returnFields)))
+
+	query, args, err := BuildSelectQuery(v.dialect, tableID, keyFields, selectFieldNames)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := v.queryRow(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query `%s`: %w", query, err)
+	}
+
+	diff := VerifyDiff{
+		TableID: tableID,
+		Key:     keyFields,
+	}
+
+	if row == nil && allFieldsKey {
+		// changed values would be reported as a missing row, so compare with the closest row.
+		row, err = v.closestRow(ctx, tableID, fields, compareFieldNames, selectFieldNames)
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			diff.Key = map[string]any{}
+			for _, fn := range compareFieldNames {
+				if valuesEqual(fields[fn], row[fn], v.timeTolerance) {
+					diff.Key[fn] = fields[fn]
+				}
+			}
+		}
+	}
+
+	if row == nil {
+		diff.Missing = true
+		v.addDiff(diff)
+		if len(returnFields) > 0 {
+			return nil, errors.New(diff.String())
+		}
+		return nil, nil
+	}
+
+	for _, fn := range slices.Sorted(slices.Values(compareFieldNames)) {
+		if !valuesEqual(fields[fn], row[fn], v.timeTolerance) {
+			diff.Fields = append(diff.Fields, VerifyFieldDiff{
+				FieldName: fn,
+				Expected:  fields[fn],
+				Actual:    row[fn],
+			})
+		}
+	}
+	if len(diff.Fields) > 0 {
+		v.addDiff(diff)
+	}
+
+	ret := map[string]any{}
+	for fn := range returnFields {
+		ret[fn] = row[fn]
+	}
+	return ret, nil
+}
+
+// closestRow returns the table row with the most fields equal to the fixture row, if at least half of them are
+// equal, or nil otherwise.
+func (v *Verifier) closestRow(ctx context.Context, tableID debefix.TableID, fields map[string]any,
+	compareFieldNames []string, selectFieldNames []string) (map[string]any, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s",
+		strings.Join(sliceMapFunc(slices.Sorted(slices.Values(selectFieldNames)), func(s string) string {
+			return v.dialect.QuoteField(s)
+		}), ", "),
+		v.dialect.QuoteTable(tableID.TableName()))
+
+	rows, err := v.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query `%s`: %w", query, err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var closest map[string]any
+	closestEqual := 0
+	for rows.Next() {
+		row, err := rowToMap(cols, rows)
+		if err != nil {
+			return nil, err
+		}
+		equal := 0
+		for _, fn := range compareFieldNames {
+			if valuesEqual(fields[fn], row[fn], v.timeTolerance) {
+				equal++
+			}
+		}
+		if equal > closestEqual {
+			closest, closestEqual = row, equal
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if closestEqual == 0 || closestEqual*2 < len(compareFieldNames) {
+		return nil, nil
+	}
+	return closest, nil
+}
+
+// queryRow returns the first row of the query, or nil if no rows were found.
+func (v *Verifier) queryRow(ctx context.Context, query string, args ...any) (map[string]any, error) {
+	rows, err := v.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	return rowToMap(cols, rows)
+}
+
+func (v *Verifier) isIgnored(tableID debefix.TableID, fieldName string) bool {
+	return slices.Contains(v.ignoreFields[""], fieldName) ||
+		slices.Contains(v.ignoreFields[tableID.TableID()], fieldName)
+}
+
+func (v *Verifier) addDiff(diff VerifyDiff) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.diffs = append(v.diffs, diff)
+}
+
+func hasAllFields(fields map[string]any, fieldNames []string) bool {
+	for _, fn := range fieldNames {
+		if _, ok := fields[fn]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares a fixture value with a database value, converting between the types commonly returned
+// by database drivers.
+func valuesEqual(expected any, actual any, timeTolerance time.Duration) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+
+	if et, ok := expected.(time.Time); ok {
+		at, ok := toTime(actual)
+		if !ok {
+			return false
+		}
+		d := et.Sub(at)
+		if d < 0 {
+			d = -d
+		}
+		return d <= timeTolerance
+	}
+
+	if eb, ok := expected.(bool); ok {
+		switch a := actual.(type) {
+		case bool:
+			return eb == a
+		default:
+			if af, ok := toFloat(actual); ok {
+				return eb == (af != 0)
+			}
+		}
+		return false
+	}
+
+	if ef, ok := toFloat(expected); ok {
+		if _, isString := expected.(string); !isString {
+			af, ok := toFloat(actual)
+			return ok && ef == af
+		}
+	}
+
+	if es, ok := toString(expected); ok {
+		if as, ok := toString(actual); ok {
+			return es == as
+		}
+	}
+
+	if reflect.DeepEqual(expected, actual) {
+		return true
+	}
+	return fmt.Sprint(expected) == fmt.Sprint(actual)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// timeLayouts are the layouts used to parse time values returned as strings.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func toTime(value any) (time.Time, bool) {
+	var s string
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
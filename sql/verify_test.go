@@ -0,0 +1,134 @@
+package sql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rrgmc/debefix-db/v2/internal/dbmock"
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	sqldb, mock, err := dbmock.New()
+	assert.NilError(t, err)
+	defer sqldb.Close()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	postColumns := []string{"post_id", "score", "tag_id", "title", "updated_at"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT active, name, tag_id FROM tags WHERE active = ? AND name = ?")).
+		WithArgs(true, "All").
+		WillReturnRows(dbmock.NewRows("active", "name", "tag_id").AddRow(int64(1), "All", int64(2)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT active, name, tag_id FROM tags WHERE active = ? AND name = ? AND tag_id = ?")).
+		WithArgs(false, "Missing", 3).
+		WillReturnRows(dbmock.NewRows("active", "name", "tag_id"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT active, name, tag_id FROM tags") + "$").
+		WillReturnRows(dbmock.NewRows("active", "name", "tag_id").
+			AddRow(int64(1), "All", int64(2)).
+			AddRow(int64(1), "Changed name", int64(4)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT active, name, tag_id FROM tags WHERE active = ? AND name = ? AND tag_id = ?")).
+		WithArgs(true, "Changed", 4).
+		WillReturnRows(dbmock.NewRows("active", "name", "tag_id"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT active, name, tag_id FROM tags") + "$").
+		WillReturnRows(dbmock.NewRows("active", "name", "tag_id").
+			AddRow(int64(1), "All", int64(2)).
+			AddRow(int64(1), "Changed name", int64(4)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT post_id, score, tag_id, title, updated_at FROM posts WHERE post_id = ?")).
+		WithArgs(1).
+		WillReturnRows(dbmock.NewRows(postColumns...).
+			AddRow(int64(1), 1.5, int64(2), "First post", now.Add(time.Second)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT post_id, score, tag_id, title, updated_at FROM posts WHERE post_id = ?")).
+		WithArgs(2).
+		WillReturnRows(dbmock.NewRows(postColumns...).
+			AddRow(int64(2), nil, int64(2), "Second post changed", now))
+
+	tableTags := debefix.TableName("tags")
+	tablePosts := debefix.TableName("posts")
+
+	data := debefix.NewData()
+
+	data.AddValues(tableTags,
+		debefix.MapValues{
+			"tag_id": debefix.ResolveValueResolve(),
+			"_refid": debefix.SetValueRefID("all"),
+			"name":   "All",
+			"active": true,
+		},
+		debefix.MapValues{
+			"tag_id": 3,
+			"name":   "Missing",
+			"active": false,
+		},
+		debefix.MapValues{
+			"tag_id": 4,
+			"name":   "Changed",
+			"active": true,
+		},
+	)
+
+	data.AddValues(tablePosts,
+		debefix.MapValues{
+			"post_id":    1,
+			"tag_id":     debefix.ValueRefID(tableTags, "all", "tag_id"),
+			"title":      "First post",
+			"score":      "1.5",
+			"updated_at": now,
+		},
+		debefix.MapValues{
+			"post_id":    2,
+			"tag_id":     debefix.ValueRefID(tableTags, "all", "tag_id"),
+			"title":      "Second post",
+			"score":      nil,
+			"updated_at": now,
+		},
+	)
+
+	verifier := NewVerifier(sqldb, DefaultQueryBuilderDialect{},
+		WithVerifyKeyFields(tablePosts, "post_id"),
+		WithVerifyTimeTolerance(time.Second),
+		WithVerifyIgnoreFields(nil, "ignored"))
+
+	_, err = debefix.Resolve(ctx, data, verifier.ResolveFunc())
+	assert.NilError(t, err)
+
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	diffs := verifier.Diffs()
+	assert.Equal(t, 3, len(diffs))
+
+	assert.Equal(t, "tags", diffs[0].TableID.TableID())
+	assert.Assert(t, diffs[0].Missing)
+
+	// compared with the closest row, as no key fields were set.
+	assert.Equal(t, "tags", diffs[1].TableID.TableID())
+	assert.DeepEqual(t, map[string]any{"active": true, "tag_id": 4}, diffs[1].Key)
+	assert.DeepEqual(t, []VerifyFieldDiff{
+		{FieldName: "name", Expected: "Changed", Actual: "Changed name"},
+	}, diffs[1].Fields)
+
+	assert.Equal(t, "posts", diffs[2].TableID.TableID())
+	assert.DeepEqual(t, map[string]any{"post_id": 2}, diffs[2].Key)
+	assert.DeepEqual(t, []VerifyFieldDiff{
+		{FieldName: "title", Expected: "Second post", Actual: "Second post changed"},
+	}, diffs[2].Fields)
+
+	assert.ErrorContains(t, verifier.Err(),
+		"table 'posts' row {post_id: 2}: field 'title' expected 'Second post' got 'Second post changed'")
+	assert.ErrorContains(t, verifier.Err(), "table 'tags' row {active: false, name: Missing, tag_id: 3}: row not found")
+	assert.ErrorContains(t, verifier.Err(),
+		"table 'tags' row {active: true, tag_id: 4}: field 'name' expected 'Changed' got 'Changed name'")
+}
+
+func TestBuildSelectQuery(t *testing.T) {
+	query, args, err := BuildSelectQuery(DefaultQueryBuilderDialect{}, tablePosts,
+		map[string]any{"post_id": 1, "deleted_at": nil}, []string{"title", "post_id"})
+	assert.NilError(t, err)
+	assert.Equal(t, "SELECT post_id, title FROM public.posts WHERE deleted_at IS NULL AND post_id = ?", query)
+	assert.DeepEqual(t, []any{1}, args)
+}
@@ -0,0 +1,201 @@
+package sqlc
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Generate generates Go source code with typed row constructors for each table of the schema.
+//
+// For each table, it generates:
+//   - a Table<Name> variable with the [debefix.TableName] of the table.
+//   - <Name>Field<Column> constants with the column names.
+//   - a <Name>Row struct with one [Value] field for each column, typed with the column Go type.
+//   - a New<Name>Row constructor which receives the values of the required columns (NOT NULL without a default
+//     value) as parameters, so they are checked at compile time. Required fields set from other rows, like foreign
+//     keys, can be replaced in the returned row using [From].
+//   - a Values method which returns the row as [debefix.MapValues], including only the fields which were set.
+func Generate(schema *Schema, options ...GenerateOption) ([]byte, error) {
+	optns := generateOptions{
+		packageName: "fixtures",
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	imports := map[string]bool{
+		"github.com/rrgmc/debefix-db/v2/sqlc": true,
+		"github.com/rrgmc/debefix/v2":         true,
+	}
+
+	var tables []templateTable
+	for _, table := range schema.Tables {
+		tt := templateTable{
+			Name:      goName(singular(table.Name)),
+			TableName: table.QualifiedName(),
+		}
+		if optns.includeSchema && table.Schema != "" {
+			tt.Name = goName(table.Schema) + tt.Name
+		}
+		for _, column := range table.Columns {
+			goType, importPath := columnGoType(column, optns.typeOverrides)
+			if importPath != "" {
+				imports[importPath] = true
+			}
+			sqlType := column.Type
+			if column.IsArray {
+				sqlType += "[]"
+			}
+			tc := templateColumn{
+				Name:       column.Name,
+				GoName:     goName(column.Name),
+				ParamName:  paramName(column.Name),
+				GoType:     goType,
+				SQLType:    sqlType,
+				IsRequired: column.Required(),
+			}
+			tt.Columns = append(tt.Columns, tc)
+			if tc.IsRequired {
+				tt.Required = append(tt.Required, tc)
+			}
+		}
+		tables = append(tables, tt)
+	}
+
+	// standard library imports first.
+	var stdImports, otherImports []string
+	for _, imp := range slices.Sorted(maps.Keys(imports)) {
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			otherImports = append(otherImports, imp)
+		} else {
+			stdImports = append(stdImports, imp)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := generateTemplate.Execute(&buf, map[string]any{
+		"Package":      optns.packageName,
+		"StdImports":   stdImports,
+		"OtherImports": otherImports,
+		"Tables":       tables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated source: %w", err)
+	}
+	return src, nil
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// WithPackageName sets the package name of the generated source. The default is "fixtures".
+func WithPackageName(packageName string) GenerateOption {
+	return func(o *generateOptions) {
+		o.packageName = packageName
+	}
+}
+
+// WithIncludeSchema prefixes the generated names with the schema name, for tables with qualified names.
+func WithIncludeSchema(includeSchema bool) GenerateOption {
+	return func(o *generateOptions) {
+		o.includeSchema = includeSchema
+	}
+}
+
+// WithTypeOverride sets the Go type of a SQL type, like "jsonb" to "map[string]any". If the type needs an import,
+// it must be passed in importPath.
+func WithTypeOverride(sqlType string, goType string, importPath string) GenerateOption {
+	return func(o *generateOptions) {
+		if o.typeOverrides == nil {
+			o.typeOverrides = map[string]goTypeInfo{}
+		}
+		o.typeOverrides[strings.ToLower(sqlType)] = goTypeInfo{goType: goType, importPath: importPath}
+	}
+}
+
+type generateOptions struct {
+	packageName   string
+	includeSchema bool
+	typeOverrides map[string]goTypeInfo
+}
+
+type templateTable struct {
+	Name      string
+	TableName string
+	Columns   []templateColumn
+	Required  []templateColumn
+}
+
+type templateColumn struct {
+	Name       string
+	GoName     string
+	ParamName  string
+	GoType     string
+	SQLType    string
+	IsRequired bool
+}
+
+var generateTemplate = template.Must(template.New("generate").Parse(`// Code generated by debefix-sqlc. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports}}
+	"{{.}}"
+{{- end}}
+{{if .StdImports}}
+{{end}}
+{{- range .OtherImports}}
+	"{{.}}"
+{{- end}}
+)
+{{range $table := .Tables}}
+// Table{{$table.Name}} is the "{{$table.TableName}}" table.
+var Table{{$table.Name}} = debefix.TableName("{{$table.TableName}}")
+
+// Column names of the "{{$table.TableName}}" table.
+const (
+{{- range $table.Columns}}
+	{{$table.Name}}Field{{.GoName}} = "{{.Name}}"
+{{- end}}
+)
+
+// {{$table.Name}}Row is a row of the "{{$table.TableName}}" table.
+type {{$table.Name}}Row struct {
+	RefID debefix.RefID // RefID of the row, if set.
+{{range $table.Columns}}
+	{{.GoName}} sqlc.Value[{{.GoType}}] // {{.SQLType}}{{if .IsRequired}} (required){{end}}
+{{- end}}
+}
+
+// New{{$table.Name}}Row returns a row of the "{{$table.TableName}}" table with the required fields set.
+func New{{$table.Name}}Row({{range $i, $c := $table.Required}}{{if $i}}, {{end}}{{$c.ParamName}} {{$c.GoType}}{{end}}) {{$table.Name}}Row {
+	return {{$table.Name}}Row{
+{{- range $table.Required}}
+		{{.GoName}}: sqlc.Set({{.ParamName}}),
+{{- end}}
+	}
+}
+
+// Values returns the row values which were set.
+func (r {{$table.Name}}Row) Values() debefix.MapValues {
+	ret := debefix.MapValues{}
+	if r.RefID != "" {
+		ret["_refid"] = debefix.SetValueRefID(r.RefID)
+	}
+{{- range $table.Columns}}
+	sqlc.AddValue(ret, {{$table.Name}}Field{{.GoName}}, r.{{.GoName}})
+{{- end}}
+	return ret
+}
+{{end}}`))
@@ -0,0 +1,159 @@
+package sqlc
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rrgmc/debefix/v2"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestParseSchema(t *testing.T) {
+	f, err := os.Open("testdata/schema.sql")
+	assert.NilError(t, err)
+	defer f.Close()
+
+	schema, err := ParseSchema(f)
+	assert.NilError(t, err)
+
+	assert.Equal(t, 3, len(schema.Tables))
+
+	tags := schema.Table("tags")
+	assert.Assert(t, tags != nil)
+	assert.DeepEqual(t, []*Column{
+		{Name: "tag_id", Type: "bigserial", NotNull: true, PrimaryKey: true, HasDefault: true},
+		{Name: "name", Type: "varchar", NotNull: true},
+		{Name: "color", Type: "text"},
+		{Name: "created_at", Type: "timestamp with time zone", NotNull: true, HasDefault: true},
+	}, tags.Columns)
+
+	users := schema.Table("public.users")
+	assert.Assert(t, users != nil)
+	assert.DeepEqual(t, []*Column{
+		{Name: "user_id", Type: "uuid", NotNull: true, PrimaryKey: true},
+		{Name: "name", Type: "text", NotNull: true},
+		{Name: "email", Type: "text", NotNull: true},
+		{Name: "profile", Type: "jsonb"},
+		{Name: "scores", Type: "integer", IsArray: true},
+		{Name: "birth_date", Type: "date"},
+	}, users.Columns)
+}
+
+func TestParseSchemaDollarQuote(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(`
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    -- CREATE TABLE ignored (id int);
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION tagged() RETURNS text AS $body$ SELECT 'a;b' $body$ LANGUAGE sql;
+
+CREATE TABLE events (
+    event_id bigserial PRIMARY KEY,
+    duration interval NOT NULL,
+    note     text DEFAULT $$it's;$$
+);`))
+	assert.NilError(t, err)
+
+	assert.Equal(t, 1, len(schema.Tables))
+	assert.DeepEqual(t, []*Column{
+		{Name: "event_id", Type: "bigserial", NotNull: true, PrimaryKey: true, HasDefault: true},
+		{Name: "duration", Type: "interval", NotNull: true},
+		{Name: "note", Type: "text", HasDefault: true},
+	}, schema.Tables[0].Columns)
+
+	goType, _ := columnGoType(schema.Tables[0].Columns[1], nil)
+	assert.Equal(t, "string", goType)
+}
+
+func TestParseSchemaMigrations(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(`
+-- +goose Up
+CREATE TABLE tags (
+    tag_id bigserial PRIMARY KEY,
+    name   text NOT NULL,
+    color  text,
+    legacy int
+);
+CREATE TABLE old_posts (post_id int PRIMARY KEY);
+CREATE TABLE comments (comment_id int PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE tags;
+
+-- +goose Up
+ALTER TABLE tags DROP COLUMN legacy, ADD COLUMN weight smallint NOT NULL DEFAULT 0;
+ALTER TABLE tags RENAME COLUMN name TO tag_name;
+ALTER TABLE tags ALTER COLUMN color TYPE varchar(20)[] USING array[color], ALTER COLUMN color SET NOT NULL;
+ALTER TABLE tags ALTER weight DROP DEFAULT;
+ALTER TABLE old_posts RENAME TO posts;
+DROP TABLE IF EXISTS comments, missing CASCADE;
+ALTER TABLE IF EXISTS missing DROP COLUMN name;`))
+	assert.NilError(t, err)
+
+	assert.Equal(t, 2, len(schema.Tables))
+	assert.DeepEqual(t, []*Column{
+		{Name: "tag_id", Type: "bigserial", NotNull: true, PrimaryKey: true, HasDefault: true},
+		{Name: "tag_name", Type: "text", NotNull: true},
+		{Name: "color", Type: "varchar", IsArray: true, NotNull: true},
+		{Name: "weight", Type: "smallint", NotNull: true},
+	}, schema.Table("tags").Columns)
+	assert.Assert(t, schema.Table("posts") != nil)
+	assert.Assert(t, schema.Table("comments") == nil)
+
+	_, err = ParseSchema(strings.NewReader(`CREATE TABLE tags (tag_id int); ALTER TABLE tags DROP COLUMN name;`))
+	assert.ErrorContains(t, err, "column 'name' not found for DROP COLUMN")
+}
+
+func TestGenerate(t *testing.T) {
+	f, err := os.Open("testdata/schema.sql")
+	assert.NilError(t, err)
+	defer f.Close()
+
+	schema, err := ParseSchema(f)
+	assert.NilError(t, err)
+
+	src, err := Generate(schema, WithPackageName("fixtures"))
+	assert.NilError(t, err)
+
+	golden.Assert(t, string(src), "schema.go.golden")
+}
+
+func TestAddValue(t *testing.T) {
+	tableTags := debefix.TableName("tags")
+
+	values := debefix.MapValues{}
+	AddValue(values, "name", Set("All"))
+	AddValue(values, "tag_id", Resolve[int64]())
+	AddValue(values, "parent_id", From[int64](debefix.ValueRefID(tableTags, "parent", "tag_id")))
+	AddValue(values, "color", Value[*string]{})
+
+	assert.DeepEqual(t, debefix.MapValues{
+		"name":      "All",
+		"tag_id":    debefix.ResolveValueResolve(),
+		"parent_id": debefix.ValueRefID(tableTags, "parent", "tag_id"),
+	}, values)
+}
+
+func TestSingular(t *testing.T) {
+	for plural, expected := range map[string]string{
+		"tags":       "tag",
+		"post_tags":  "post_tag",
+		"categories": "category",
+		"ties":       "tie",
+		"People":     "Person",
+		"addresses":  "address",
+		"boxes":      "box",
+		"people":     "person",
+		"status":     "status",
+		"news":       "news",
+		"user_data":  "user_data",
+	} {
+		assert.Equal(t, expected, singular(plural), plural)
+	}
+}
@@ -0,0 +1,631 @@
+package sqlc
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Schema is a list of tables parsed from a sqlc schema file.
+type Schema struct {
+	Tables []*Table
+}
+
+// Table is a table parsed from a CREATE TABLE statement.
+type Table struct {
+	Schema  string // schema name, if the table name was qualified.
+	Name    string
+	Columns []*Column
+}
+
+// QualifiedName returns the table name including the schema, if set.
+func (t *Table) QualifiedName() string {
+	if t.Schema != "" {
+		return t.Schema + "." + t.Name
+	}
+	return t.Name
+}
+
+// Column is a table column.
+type Column struct {
+	Name       string
+	Type       string // the SQL type, lowercase.
+	IsArray    bool
+	NotNull    bool
+	PrimaryKey bool
+	HasDefault bool // the column has a default value, or is generated by the database.
+}
+
+// Required returns whether the column value must be set on insert.
+func (c *Column) Required() bool {
+	return c.NotNull && !c.HasDefault
+}
+
+// Nullable returns whether the column accepts NULL values.
+func (c *Column) Nullable() bool {
+	return !c.NotNull && !c.PrimaryKey
+}
+
+// ParseSchema parses the CREATE TABLE, DROP TABLE and ALTER TABLE statements of a sqlc schema file, applying them in
+// order. Other statements are ignored. The down sections of goose ("-- +goose Down") and dbmate ("-- migrate:down")
+// migrations are skipped.
+func ParseSchema(r io.Reader) (*Schema, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Schema{}
+	for _, stmt := range splitStatements(stripComments(stripDownMigrations(string(src)))) {
+		tokens := tokenize(stmt)
+		switch {
+		case matchTokens(tokens, "create", "table"), matchTokens(tokens, "create", "unlogged", "table"),
+			matchTokens(tokens, "create", "temporary", "table"), matchTokens(tokens, "create", "temp", "table"):
+			table, err := parseCreateTable(stmt)
+			if err != nil {
+				return nil, err
+			}
+			ret.Tables = append(ret.Tables, table)
+		case matchTokens(tokens, "drop", "table"):
+			if err := ret.parseDropTable(stmt); err != nil {
+				return nil, err
+			}
+		case matchTokens(tokens, "alter", "table"):
+			if err := ret.parseAlterTable(tokens); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ret, nil
+}
+
+// Table returns a table by its name, which may be qualified by the schema.
+func (s *Schema) Table(name string) *Table {
+	for _, table := range s.Tables {
+		if table.QualifiedName() == name || (table.Schema == "" && table.Name == name) {
+			return table
+		}
+	}
+	return nil
+}
+
+func parseCreateTable(stmt string) (*Table, error) {
+	start := strings.Index(stmt, "(")
+	end := strings.LastIndex(stmt, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid CREATE TABLE statement: %s", stmt)
+	}
+
+	header := tokenize(stmt[:start])
+	header = slices.DeleteFunc(header, func(s string) bool {
+		return slices.Contains([]string{"create", "table", "unlogged", "temporary", "temp", "if", "not", "exists"},
+			strings.ToLower(s))
+	})
+	if len(header) != 1 {
+		return nil, fmt.Errorf("invalid CREATE TABLE statement: %s", stmt)
+	}
+
+	table := &Table{}
+	table.Schema, table.Name = splitQualifiedName(header[0])
+
+	for _, def := range splitTopLevel(stmt[start+1:end], ',') {
+		tokens := tokenize(def)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch strings.ToLower(tokens[0]) {
+		case "constraint", "primary", "unique", "foreign", "check", "exclude", "like":
+			if pk := primaryKeyColumns(def); len(pk) > 0 {
+				for _, column := range table.Columns {
+					if slices.Contains(pk, column.Name) {
+						column.PrimaryKey = true
+						column.NotNull = true
+					}
+				}
+			}
+			continue
+		}
+		column, err := parseColumn(tokens)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing table '%s': %w", table.QualifiedName(), err)
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	return table, nil
+}
+
+// Column returns a column by its name.
+func (t *Table) Column(name string) *Column {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+func (s *Schema) parseDropTable(stmt string) error {
+	// DROP TABLE [IF EXISTS] name [, ...] [CASCADE | RESTRICT]
+	tokens := tokenize(stmt)[2:]
+	ifExists := matchTokens(tokens, "if", "exists")
+	if ifExists {
+		tokens = tokens[2:]
+	}
+	tokens = slices.DeleteFunc(slices.Clone(tokens), func(s string) bool {
+		return slices.Contains([]string{"cascade", "restrict"}, strings.ToLower(s))
+	})
+	for _, name := range splitTopLevel(strings.Join(tokens, " "), ',') {
+		table := s.Table(unquoteQualifiedName(name))
+		if table == nil {
+			if ifExists {
+				continue
+			}
+			return fmt.Errorf("table '%s' not found for DROP TABLE", name)
+		}
+		s.Tables = slices.DeleteFunc(s.Tables, func(t *Table) bool {
+			return t == table
+		})
+	}
+	return nil
+}
+
+func (s *Schema) parseAlterTable(tokens []string) error {
+	// ALTER TABLE [IF EXISTS] [ONLY] name action [, ...]
+	tokens = slices.DeleteFunc(slices.Clone(tokens[2:]), func(s string) bool {
+		return slices.Contains([]string{"only"}, strings.ToLower(s))
+	})
+	ifExists := matchTokens(tokens, "if", "exists")
+	if ifExists {
+		tokens = tokens[2:]
+	}
+	if len(tokens) < 2 {
+		return nil
+	}
+	table := s.Table(unquoteQualifiedName(tokens[0]))
+	if table == nil {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("table '%s' not found for ALTER TABLE", tokens[0])
+	}
+	for _, action := range splitTopLevel(strings.Join(tokens[1:], " "), ',') {
+		if err := table.parseAlterTableAction(tokenize(action)); err != nil {
+			return fmt.Errorf("error parsing table '%s': %w", table.QualifiedName(), err)
+		}
+	}
+	return nil
+}
+
+// parseAlterTableAction applies an ALTER TABLE action which changes the columns or name of the table. Other actions
+// are ignored.
+func (t *Table) parseAlterTableAction(tokens []string) error {
+	switch {
+	case matchTokens(tokens, "add"):
+		// ADD [COLUMN] [IF NOT EXISTS] definition
+		tokens = tokens[1:]
+		if matchTokens(tokens, "column") {
+			tokens = tokens[1:]
+		}
+		ifNotExists := matchTokens(tokens, "if", "not", "exists")
+		if ifNotExists {
+			tokens = tokens[3:]
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+		switch strings.ToLower(tokens[0]) {
+		case "constraint", "primary", "unique", "foreign", "check", "exclude":
+			return nil
+		}
+		column, err := parseColumn(tokens)
+		if err != nil {
+			return err
+		}
+		if t.Column(column.Name) != nil {
+			if ifNotExists {
+				return nil
+			}
+			return fmt.Errorf("column '%s' already exists", column.Name)
+		}
+		t.Columns = append(t.Columns, column)
+	case matchTokens(tokens, "drop"):
+		// DROP [COLUMN] [IF EXISTS] name [CASCADE | RESTRICT]
+		tokens = tokens[1:]
+		if matchTokens(tokens, "column") {
+			tokens = tokens[1:]
+		}
+		ifExists := matchTokens(tokens, "if", "exists")
+		if ifExists {
+			tokens = tokens[2:]
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+		switch strings.ToLower(tokens[0]) {
+		case "constraint":
+			return nil
+		}
+		column := t.Column(unquoteIdentifier(tokens[0]))
+		if column == nil {
+			if ifExists {
+				return nil
+			}
+			return fmt.Errorf("column '%s' not found for DROP COLUMN", tokens[0])
+		}
+		t.Columns = slices.DeleteFunc(t.Columns, func(c *Column) bool {
+			return c == column
+		})
+	case matchTokens(tokens, "rename", "to") && len(tokens) == 3:
+		// RENAME TO new_name
+		t.Name = unquoteIdentifier(tokens[2])
+	case matchTokens(tokens, "rename"):
+		// RENAME [COLUMN] name TO new_name
+		tokens = tokens[1:]
+		if matchTokens(tokens, "column") {
+			tokens = tokens[1:]
+		}
+		if len(tokens) != 3 || !strings.EqualFold(tokens[1], "to") {
+			return nil
+		}
+		column := t.Column(unquoteIdentifier(tokens[0]))
+		if column == nil {
+			return fmt.Errorf("column '%s' not found for RENAME COLUMN", tokens[0])
+		}
+		column.Name = unquoteIdentifier(tokens[2])
+	case matchTokens(tokens, "alter"):
+		// ALTER [COLUMN] name [SET DATA] TYPE type [USING expression]
+		// ALTER [COLUMN] name { SET | DROP } { DEFAULT | NOT NULL }
+		tokens = tokens[1:]
+		if matchTokens(tokens, "column") {
+			tokens = tokens[1:]
+		}
+		if len(tokens) < 2 {
+			return nil
+		}
+		column := t.Column(unquoteIdentifier(tokens[0]))
+		if column == nil {
+			return fmt.Errorf("column '%s' not found for ALTER COLUMN", tokens[0])
+		}
+		tokens = tokens[1:]
+		if matchTokens(tokens, "set", "data") {
+			tokens = tokens[2:]
+		}
+		switch {
+		case matchTokens(tokens, "type"):
+			if idx := slices.IndexFunc(tokens, func(s string) bool {
+				return strings.EqualFold(s, "using") || strings.EqualFold(s, "collate")
+			}); idx >= 0 {
+				tokens = tokens[:idx]
+			}
+			// parse the type as a column definition, with the "type" keyword as the name.
+			typeColumn, err := parseColumn(tokens)
+			if err != nil {
+				return err
+			}
+			column.Type, column.IsArray = typeColumn.Type, typeColumn.IsArray
+		case matchTokens(tokens, "set", "not", "null"):
+			column.NotNull = true
+		case matchTokens(tokens, "drop", "not", "null"):
+			column.NotNull = column.PrimaryKey
+		case matchTokens(tokens, "set", "default"):
+			column.HasDefault = true
+		case matchTokens(tokens, "drop", "default"):
+			column.HasDefault = false
+		}
+	}
+	return nil
+}
+
+// columnConstraintKeywords are the keywords that end the column type.
+var columnConstraintKeywords = []string{"not", "null", "default", "primary", "references", "unique", "check",
+	"constraint", "generated", "collate"}
+
+func parseColumn(tokens []string) (*Column, error) {
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("invalid column definition: %s", strings.Join(tokens, " "))
+	}
+
+	column := &Column{
+		Name: unquoteIdentifier(tokens[0]),
+	}
+
+	var typeTokens []string
+	i := 1
+	for ; i < len(tokens); i++ {
+		if slices.Contains(columnConstraintKeywords, strings.ToLower(tokens[i])) {
+			break
+		}
+		typeTokens = append(typeTokens, strings.ToLower(tokens[i]))
+	}
+	columnType := strings.Join(typeTokens, " ")
+	if strings.HasSuffix(columnType, "[]") {
+		column.IsArray = true
+		columnType = strings.TrimSpace(strings.TrimSuffix(columnType, "[]"))
+	}
+	// remove type modifiers, like varchar(255).
+	if idx := strings.Index(columnType, "("); idx >= 0 {
+		if endIdx := strings.LastIndex(columnType, ")"); endIdx > idx {
+			columnType = strings.TrimSpace(columnType[:idx] + columnType[endIdx+1:])
+		}
+	}
+	if strings.HasSuffix(columnType, "[]") {
+		column.IsArray = true
+		columnType = strings.TrimSpace(strings.TrimSuffix(columnType, "[]"))
+	}
+	column.Type = columnType
+
+	switch column.Type {
+	case "serial", "serial4", "bigserial", "serial8", "smallserial", "serial2":
+		column.NotNull = true
+		column.HasDefault = true
+	}
+
+	for ; i < len(tokens); i++ {
+		switch strings.ToLower(tokens[i]) {
+		case "not":
+			if i+1 < len(tokens) && strings.EqualFold(tokens[i+1], "null") {
+				column.NotNull = true
+				i++
+			}
+		case "primary":
+			column.PrimaryKey = true
+			column.NotNull = true
+		case "default", "generated":
+			column.HasDefault = true
+		}
+	}
+
+	return column, nil
+}
+
+// primaryKeyColumns returns the column names of a PRIMARY KEY table constraint.
+func primaryKeyColumns(def string) []string {
+	lower := strings.ToLower(def)
+	idx := strings.Index(lower, "primary key")
+	if idx < 0 {
+		return nil
+	}
+	rest := def[idx:]
+	start := strings.Index(rest, "(")
+	end := strings.Index(rest, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+	var ret []string
+	for _, col := range strings.Split(rest[start+1:end], ",") {
+		ret = append(ret, unquoteIdentifier(strings.TrimSpace(col)))
+	}
+	return ret
+}
+
+// stripDownMigrations removes the down sections of goose and dbmate migrations, which revert the schema changes of
+// the up sections.
+func stripDownMigrations(src string) string {
+	var b strings.Builder
+	down := false
+	for _, line := range strings.SplitAfter(src, "\n") {
+		switch strings.ToLower(strings.Join(strings.Fields(line), " ")) {
+		case "-- +goose down", "-- migrate:down":
+			down = true
+		case "-- +goose up", "-- migrate:up":
+			down = false
+		}
+		if !down {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// stripComments removes SQL comments.
+func stripComments(src string) string {
+	var b strings.Builder
+	inString := false
+	for i := 0; i < len(src); i++ {
+		switch {
+		case !inString && dollarQuoteEnd(src, i) > i:
+			end := dollarQuoteEnd(src, i)
+			b.WriteString(src[i:end])
+			i = end - 1
+		case src[i] == '\'':
+			inString = !inString
+			b.WriteByte(src[i])
+		case !inString && strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case !inString && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return b.String()
+}
+
+// splitStatements splits SQL statements by ';'.
+func splitStatements(src string) []string {
+	var ret []string
+	for _, stmt := range splitTopLevel(src, ';') {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			ret = append(ret, stmt)
+		}
+	}
+	return ret
+}
+
+// splitTopLevel splits the string by the separator, ignoring separators inside parenthesis, quotes and dollar
+// quotes.
+func splitTopLevel(src string, sep byte) []string {
+	var ret []string
+	depth := 0
+	var quote byte
+	last := 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case dollarQuoteEnd(src, i) > i:
+			i = dollarQuoteEnd(src, i) - 1
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			ret = append(ret, strings.TrimSpace(src[last:i]))
+			last = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(src[last:]); rest != "" {
+		ret = append(ret, rest)
+	}
+	return ret
+}
+
+// dollarQuoteEnd returns the position after the end of the postgres dollar quoted string starting at i, like
+// "$$body$$" or "$tag$body$tag$", or i if there isn't one starting at i. An unterminated string ends at the end
+// of src.
+func dollarQuoteEnd(src string, i int) int {
+	if src[i] != '$' || (i > 0 && isIdentifierChar(src[i-1])) {
+		return i
+	}
+	j := i + 1
+	for j < len(src) && src[j] != '$' {
+		if !isIdentifierChar(src[j]) || (j == i+1 && src[j] >= '0' && src[j] <= '9') {
+			return i
+		}
+		j++
+	}
+	if j >= len(src) {
+		return i
+	}
+	tag := src[i : j+1]
+	end := strings.Index(src[j+1:], tag)
+	if end < 0 {
+		return len(src)
+	}
+	return j + 1 + end + len(tag)
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// tokenize splits a statement in tokens, keeping parenthesized expressions and quoted identifiers together.
+func tokenize(src string) []string {
+	var ret []string
+	var cur strings.Builder
+	depth := 0
+	var quote byte
+	flush := func() {
+		if cur.Len() > 0 {
+			ret = append(ret, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			cur.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+		case dollarQuoteEnd(src, i) > i:
+			end := dollarQuoteEnd(src, i)
+			cur.WriteString(src[i:end])
+			i = end - 1
+		case c == '\'' || c == '"':
+			quote = c
+			cur.WriteByte(c)
+		case c == '(':
+			depth++
+			cur.WriteByte(c)
+		case c == ')':
+			depth--
+			cur.WriteByte(c)
+		case depth == 0 && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+
+	// join type modifiers separated by spaces, like "varchar (255)" and "int []".
+	var joined []string
+	for _, token := range ret {
+		if len(joined) > 0 && (strings.HasPrefix(token, "(") || strings.HasPrefix(token, "[")) {
+			joined[len(joined)-1] += token
+			continue
+		}
+		joined = append(joined, token)
+	}
+	return joined
+}
+
+// matchTokens returns whether the tokens start with the passed case-insensitive words.
+func matchTokens(tokens []string, words ...string) bool {
+	if len(tokens) < len(words) {
+		return false
+	}
+	for i, word := range words {
+		if !strings.EqualFold(tokens[i], word) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitQualifiedName(name string) (schema string, table string) {
+	parts := splitTopLevelQuoted(name)
+	if len(parts) == 2 {
+		return unquoteIdentifier(parts[0]), unquoteIdentifier(parts[1])
+	}
+	return "", unquoteIdentifier(name)
+}
+
+func unquoteQualifiedName(name string) string {
+	schema, table := splitQualifiedName(name)
+	if schema != "" {
+		return schema + "." + table
+	}
+	return table
+}
+
+// splitTopLevelQuoted splits a qualified name by '.', ignoring dots inside quotes.
+func splitTopLevelQuoted(name string) []string {
+	var ret []string
+	inQuote := false
+	last := 0
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '"':
+			inQuote = !inQuote
+		case name[i] == '.' && !inQuote:
+			ret = append(ret, name[last:i])
+			last = i + 1
+		}
+	}
+	return append(ret, name[last:])
+}
+
+func unquoteIdentifier(s string) string {
+	if len(s) >= 2 && ((s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '`' && s[len(s)-1] == '`')) {
+		return strings.ReplaceAll(s[1:len(s)-1], s[:1]+s[:1], s[:1])
+	}
+	return strings.ToLower(s)
+}
@@ -0,0 +1,27 @@
+-- tags
+CREATE TABLE tags (
+    tag_id     BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL UNIQUE,
+    color      TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+/* users of the blog */
+CREATE TABLE IF NOT EXISTS public.users (
+    user_id    UUID NOT NULL,
+    name       text NOT NULL,
+    email      text NOT NULL,
+    profile    JSONB,
+    scores     integer[],
+    CONSTRAINT users_pk PRIMARY KEY (user_id)
+);
+
+CREATE TABLE post_tags (
+    post_id UUID NOT NULL REFERENCES posts (post_id),
+    tag_id  BIGINT NOT NULL REFERENCES tags (tag_id),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+ALTER TABLE public.users ADD COLUMN birth_date date;
+
+CREATE INDEX tags_name_idx ON tags (name);
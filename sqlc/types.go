@@ -0,0 +1,173 @@
+package sqlc
+
+import (
+	"go/token"
+	"strings"
+)
+
+type goTypeInfo struct {
+	goType     string
+	importPath string
+}
+
+// sqlGoTypes maps SQL types to Go types.
+var sqlGoTypes = map[string]goTypeInfo{
+	"smallint":                    {goType: "int16"},
+	"int2":                        {goType: "int16"},
+	"smallserial":                 {goType: "int16"},
+	"serial2":                     {goType: "int16"},
+	"integer":                     {goType: "int32"},
+	"int":                         {goType: "int32"},
+	"int4":                        {goType: "int32"},
+	"serial":                      {goType: "int32"},
+	"serial4":                     {goType: "int32"},
+	"bigint":                      {goType: "int64"},
+	"int8":                        {goType: "int64"},
+	"bigserial":                   {goType: "int64"},
+	"serial8":                     {goType: "int64"},
+	"real":                        {goType: "float32"},
+	"float4":                      {goType: "float32"},
+	"double precision":            {goType: "float64"},
+	"float8":                      {goType: "float64"},
+	"float":                       {goType: "float64"},
+	"numeric":                     {goType: "string"},
+	"decimal":                     {goType: "string"},
+	"money":                       {goType: "string"},
+	"boolean":                     {goType: "bool"},
+	"bool":                        {goType: "bool"},
+	"text":                        {goType: "string"},
+	"varchar":                     {goType: "string"},
+	"character varying":           {goType: "string"},
+	"char":                        {goType: "string"},
+	"character":                   {goType: "string"},
+	"bpchar":                      {goType: "string"},
+	"citext":                      {goType: "string"},
+	"inet":                        {goType: "string"},
+	"cidr":                        {goType: "string"},
+	"macaddr":                     {goType: "string"},
+	"bytea":                       {goType: "[]byte"},
+	"blob":                        {goType: "[]byte"},
+	"json":                        {goType: "json.RawMessage", importPath: "encoding/json"},
+	"jsonb":                       {goType: "json.RawMessage", importPath: "encoding/json"},
+	"uuid":                        {goType: "uuid.UUID", importPath: "github.com/google/uuid"},
+	"date":                        {goType: "time.Time", importPath: "time"},
+	"time":                        {goType: "time.Time", importPath: "time"},
+	"time without time zone":      {goType: "time.Time", importPath: "time"},
+	"time with time zone":         {goType: "time.Time", importPath: "time"},
+	"timetz":                      {goType: "time.Time", importPath: "time"},
+	"timestamp":                   {goType: "time.Time", importPath: "time"},
+	"timestamp without time zone": {goType: "time.Time", importPath: "time"},
+	"timestamp with time zone":    {goType: "time.Time", importPath: "time"},
+	"timestamptz":                 {goType: "time.Time", importPath: "time"},
+	"datetime":                    {goType: "time.Time", importPath: "time"},
+	"interval":                    {goType: "string"}, // drivers don't encode time.Duration as an interval.
+}
+
+// columnGoType returns the Go type of the column, and the import path needed by it.
+// Nullable columns are returned as pointers, and unknown types as "any".
+func columnGoType(column *Column, overrides map[string]goTypeInfo) (string, string) {
+	info, ok := overrides[column.Type]
+	if !ok {
+		info, ok = sqlGoTypes[column.Type]
+	}
+	if !ok {
+		return "any", ""
+	}
+	goType := info.goType
+	if column.IsArray {
+		goType = "[]" + goType
+	} else if column.Nullable() && goType != "any" && !strings.HasPrefix(goType, "[]") &&
+		!strings.HasPrefix(goType, "map[") && goType != "json.RawMessage" {
+		goType = "*" + goType
+	}
+	return goType, info.importPath
+}
+
+// commonInitialisms are words which are generated in uppercase in Go names.
+var commonInitialisms = map[string]bool{
+	"api": true, "db": true, "html": true, "http": true, "id": true, "ip": true, "json": true, "sql": true,
+	"uid": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// goName converts a snake_case SQL name to an exported Go name.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' ' || r == '.'
+	}) {
+		lower := strings.ToLower(part)
+		if commonInitialisms[lower] {
+			b.WriteString(strings.ToUpper(lower))
+			continue
+		}
+		b.WriteString(strings.ToUpper(lower[:1]) + lower[1:])
+	}
+	ret := b.String()
+	if ret == "" || !token.IsIdentifier(ret) {
+		ret = "X" + ret
+	}
+	return ret
+}
+
+// paramName converts a snake_case SQL name to an unexported Go name for use as a parameter.
+func paramName(name string) string {
+	ret := goName(name)
+	for i, r := range ret {
+		if r < 'A' || r > 'Z' {
+			if i > 1 {
+				// keep the last uppercase letter of an initialism, like "IDValue" => "idValue".
+				i--
+			}
+			ret = strings.ToLower(ret[:i]) + ret[i:]
+			break
+		}
+		if i == len(ret)-1 {
+			ret = strings.ToLower(ret)
+		}
+	}
+	if token.IsKeyword(ret) || ret == "ret" || ret == "sqlc" || ret == "debefix" {
+		ret += "_"
+	}
+	return ret
+}
+
+// singularUncountable are words which have the same singular and plural forms.
+var singularUncountable = map[string]bool{
+	"data": true, "equipment": true, "information": true, "metadata": true, "news": true, "series": true,
+	"species": true, "status": true,
+}
+
+// singularIrregular are irregular plural words.
+var singularIrregular = map[string]string{
+	"children": "child", "men": "man", "people": "person", "women": "woman",
+}
+
+// singular returns the singular of the last word of a plural name, using basic English rules, keeping the case of
+// its first letter. Names ending in "ies" with more than 4 letters end in "y", like "categories" to "category",
+// while shorter ones like "ties" only lose the "s".
+// The rules are the same as the ent package singular, and must be kept in sync.
+func singular(name string) string {
+	idx := strings.LastIndexAny(name, "_-. ") + 1
+	prefix, word := name[:idx], name[idx:]
+	lower := strings.ToLower(word)
+	switch {
+	case singularUncountable[lower]:
+		return name
+	case singularIrregular[lower] != "":
+		irregular := singularIrregular[lower]
+		if word[:1] != lower[:1] {
+			irregular = strings.ToUpper(irregular[:1]) + irregular[1:]
+		}
+		return prefix + irregular
+	case strings.HasSuffix(lower, "ies") && len(lower) > 4:
+		return prefix + word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "ches"),
+		strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "zzes"):
+		return prefix + word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return name
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		return prefix + word[:len(word)-1]
+	}
+	return name
+}
@@ -0,0 +1,50 @@
+package sqlc
+
+import (
+	"github.com/rrgmc/debefix/v2"
+)
+
+// Value is a field value of type T used by the generated row types. It can be a fixed value of the column type,
+// a [debefix.Value] resolved at resolve time, or a value generated by the database.
+// The zero value means the field is not set, and it is not added to the row values.
+type Value[T any] struct {
+	value any
+	isSet bool
+}
+
+// Set returns a fixed field value.
+func Set[T any](value T) Value[T] {
+	return Value[T]{value: value, isSet: true}
+}
+
+// From returns a field value which is resolved at resolve time, like [debefix.ValueRefID].
+func From[T any](value debefix.Value) Value[T] {
+	return Value[T]{value: value, isSet: true}
+}
+
+// Resolve returns a field value which is generated by the database, using [debefix.ResolveValueResolve].
+func Resolve[T any]() Value[T] {
+	return ResolveWith[T](debefix.ResolveValueResolve())
+}
+
+// ResolveWith returns a field value which is generated by the database, parsed by the passed ResolveValue.
+func ResolveWith[T any](value debefix.ResolveValue) Value[T] {
+	return Value[T]{value: value, isSet: true}
+}
+
+// IsSet returns whether the value was set.
+func (v Value[T]) IsSet() bool {
+	return v.isSet
+}
+
+// Get returns the field value, and whether it was set.
+func (v Value[T]) Get() (any, bool) {
+	return v.value, v.isSet
+}
+
+// AddValue adds the field value to values if it was set.
+func AddValue[T any](values debefix.MapValues, fieldName string, value Value[T]) {
+	if v, ok := value.Get(); ok {
+		values[fieldName] = v
+	}
+}